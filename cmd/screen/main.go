@@ -2,16 +2,24 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"crypto/ed25519"
 	"fmt"
 	"log"
+	"net/http"
 	"mnemoCast-client/internal/ads"
 	"mnemoCast-client/internal/client"
 	"mnemoCast-client/internal/config"
 	"mnemoCast-client/internal/credentials"
+	"mnemoCast-client/internal/db"
+	"mnemoCast-client/internal/events"
 	"mnemoCast-client/internal/heartbeat"
 	"mnemoCast-client/internal/identity"
 	"mnemoCast-client/internal/models"
 	"mnemoCast-client/internal/player"
+	"mnemoCast-client/internal/retry"
+	"mnemoCast-client/internal/transport"
+	"mnemoCast-client/pkg/signing"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -21,6 +29,9 @@ import (
 )
 
 func main() {
+	// Release the screen database on the way out so tools like test-ads can open it
+	defer db.CloseAll()
+
 	// Get user's home directory
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -80,15 +91,58 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	fmt.Printf("[OK] Ad Server URL: %s\n", screenConfig.AdServerURL)
+	serverEndpoints := screenConfig.ServerEndpoints()
+	if len(serverEndpoints) > 1 {
+		fmt.Println("[OK] Ad Server Endpoints (failover):")
+		for _, endpoint := range serverEndpoints {
+			fmt.Printf("   - %s (priority %d)\n", endpoint.URL, endpoint.Priority)
+		}
+	} else {
+		fmt.Printf("[OK] Ad Server URL: %s\n", serverEndpoints[0].URL)
+	}
 	fmt.Printf("   Heartbeat Interval: %d seconds\n", screenConfig.HeartbeatInterval)
 	if screenConfig.AdFetchInterval > 0 {
 		fmt.Printf("   Ad Fetch Interval: %d seconds\n", screenConfig.AdFetchInterval)
 	}
+	if screenConfig.AuthMode == models.AuthModeHMAC {
+		fmt.Println("   Auth Mode: HMAC request signing")
+	}
+
+	// Pin the playlist signing key; playlists that don't verify against it are never played
+	var playlistKey ed25519.PublicKey
+	if screenConfig.PlaylistPublicKey != "" {
+		playlistKey, err = signing.ParsePublicKey(screenConfig.PlaylistPublicKey)
+		if err != nil {
+			log.Fatalf("Invalid playlist public key: %v", err)
+		}
+		fmt.Println("   Playlist Signatures: required")
+	}
 	fmt.Printf("   Retry Attempts: %d\n", screenConfig.RetryAttempts)
-	fmt.Printf("   Retry Delay: %d seconds\n", screenConfig.RetryDelay)
+	fmt.Printf("   Retry Delay: %d seconds (max %d seconds)\n", screenConfig.RetryDelay, screenConfig.RetryMaxDelay)
 	fmt.Println()
 
+	// Build the HTTP transport shared by the API client and media downloader
+	httpTransport, err := transport.New(screenConfig.Transport)
+	if err != nil {
+		log.Fatalf("Failed to configure HTTP transport: %v", err)
+	}
+	if screenConfig.Transport.CAFile != "" || screenConfig.Transport.ClientCertFile != "" {
+		for _, endpoint := range serverEndpoints {
+			if strings.HasPrefix(endpoint.URL, "http://") {
+				fmt.Printf("[WARN] TLS settings are configured but ad server URL %s uses plain http://\n", endpoint.URL)
+				fmt.Println()
+			}
+		}
+	}
+
+	// Open the durable proof-of-play queue; records stay on disk until the server acknowledges them
+	eventQueue, err := events.NewQueue(configDir, screenConfig.EventQueueMaxSize)
+	if err != nil {
+		log.Printf("[WARN] Proof-of-play queue unavailable: %v", err)
+	} else if pending := eventQueue.Len(); pending > 0 {
+		fmt.Printf("[INFO] %d proof-of-play records pending upload\n\n", pending)
+	}
+
 	// Update identity in config if needed
 	if screenConfig.Identity.ID == "" {
 		screenConfig.Identity = *screenIdentity
@@ -161,12 +215,34 @@ func main() {
 	// Initialize ad server client, heartbeat, ad fetcher, and player if credentials exist
 	var heartbeatScheduler *heartbeat.Scheduler
 	var adFetcher *ads.Fetcher
+	var adMerger *ads.Merger
 	var adPlayer *player.Player
 	var adClient *client.Client
+	var popUploader *client.Uploader
+	healthCtx, stopHealthChecks := context.WithCancel(context.Background())
+	defer stopHealthChecks()
 	
 	if screenID != "" && passkey != "" {
 		// Create ad server client with screen ID and passkey
-		adClient = client.NewClient(screenConfig.AdServerURL, screenID, passkey)
+		adClient = client.NewClient(serverEndpoints[0].URL, screenID, passkey)
+		adClient.SetEndpoints(serverEndpoints, time.Duration(screenConfig.EndpointFailBackDelay)*time.Second)
+		adClient.SetRetryPolicy(retry.FromConfig(screenConfig))
+		adClient.SetTransport(httpTransport, transport.RequestTimeout(screenConfig.Transport))
+		adClient.SetSigning(screenConfig.AuthMode == models.AuthModeHMAC)
+		adClient.SetMaxResponseSize(screenConfig.MaxAdResponseSize)
+
+		// Resume the session from a previous run and keep its refresh token encrypted on disk
+		adClient.SetRefreshToken(credManager.GetRefreshToken())
+		adClient.SetOnSessionUpdated(func(refreshToken string) {
+			if err := credManager.SetRefreshToken(refreshToken); err != nil {
+				log.Printf("[WARN] Failed to persist session refresh token: %v", err)
+			}
+		})
+		
+		// Health check the endpoints so the client can fail over early and fail back once recovered
+		if len(serverEndpoints) > 1 {
+			adClient.StartHealthChecks(healthCtx, time.Duration(screenConfig.EndpointHealthInterval)*time.Second)
+		}
 		
 		// Try to connect/authenticate with server (optional - heartbeat will work even if this fails)
 		fmt.Println("Connecting to ad server...")
@@ -174,7 +250,14 @@ func main() {
 		connectedScreen, err := adClient.Connect()
 		if err != nil {
 			log.Printf("[WARN] Connection failed: %v", err)
-			fmt.Println("   [WARN] Could not connect to ad server")
+			switch {
+			case client.IsAuthError(err):
+				fmt.Println("   [ERROR] Server rejected the screen ID or passkey")
+			case client.IsNotFoundError(err):
+				fmt.Printf("   [ERROR] Screen %s is not registered on the server\n", screenID)
+			default:
+				fmt.Println("   [WARN] Could not connect to ad server")
+			}
 			fmt.Println("   Note: Heartbeat will still be attempted...")
 		} else {
 			fmt.Printf("   [OK] Connected successfully!\n")
@@ -223,12 +306,32 @@ func main() {
 			identityManager,
 			screenID,
 			screenConfig.HeartbeatInterval,
-			screenConfig.RetryAttempts,
-			screenConfig.RetryDelay,
 		)
+		heartbeatScheduler.SetIntervalBounds(
+			time.Duration(screenConfig.MinPollInterval)*time.Second,
+			time.Duration(screenConfig.MaxPollInterval)*time.Second,
+		)
+		if heartbeatHistory, err := heartbeat.NewHistory(configDir, heartbeat.DefaultHistorySize); err != nil {
+			log.Printf("[WARN] Heartbeat history disabled: %v", err)
+		} else {
+			heartbeatScheduler.SetHistory(heartbeatHistory)
+		}
 		heartbeatScheduler.Start()
 		fmt.Printf("   [OK] Heartbeat scheduler started (interval: %d seconds)\n", screenConfig.HeartbeatInterval)
 
+		// Start proof-of-play uploader
+		if eventQueue != nil {
+			popUploader = client.NewUploader(
+				adClient,
+				screenID,
+				eventQueue,
+				screenConfig.ProofOfPlayUploadInterval,
+				screenConfig.ProofOfPlayBatchSize,
+			)
+			popUploader.Start()
+			fmt.Printf("   [OK] Proof-of-play uploader started (interval: %d seconds)\n", screenConfig.ProofOfPlayUploadInterval)
+		}
+
 		// Start ad fetcher
 		if screenConfig.AdFetchInterval > 0 {
 			fmt.Println()
@@ -238,8 +341,12 @@ func main() {
 				screenID,
 				configDir,
 				screenConfig.AdFetchInterval,
-				screenConfig.RetryAttempts,
-				screenConfig.RetryDelay,
+			)
+			adFetcher.GetStorage().SetHistoryLimit(screenConfig.PlaylistHistorySize)
+			adFetcher.SetPlaylistKey(playlistKey)
+			adFetcher.SetIntervalBounds(
+				time.Duration(screenConfig.MinPollInterval)*time.Second,
+				time.Duration(screenConfig.MaxPollInterval)*time.Second,
 			)
 			
 			// Try to load existing ads from storage
@@ -250,8 +357,7 @@ func main() {
 				}
 			}
 			
-			adFetcher.Start()
-			fmt.Printf("   [OK] Ad fetcher started (interval: %d seconds)\n", screenConfig.AdFetchInterval)
+			adFetcher.SetPushEnabled(screenConfig.PushEnabled)
 			fmt.Printf("   [INFO] Ads will be stored in: %s/ads/\n", configDir)
 			
 			// Initialize and start ad player
@@ -259,17 +365,59 @@ func main() {
 			fmt.Println("Starting ad player...")
 			adStorage := adFetcher.GetStorage()
 			adPlayer = player.NewPlayer(adStorage, screenConfig)
+			adPlayer.SetEventQueue(eventQueue)
+			adPlayer.SetTransport(httpTransport)
+			// Ads stop running once the server hasn't confirmed the playlist for too long
+			adPlayer.SetLastFetch(adFetcher.GetLastFetch)
+			
+			// Merge any other configured ad sources with the ad server's playlist
+			adMerger = newAdMerger(screenConfig, adStorage.GetAdsDir(), screenID, passkey, httpTransport, playlistKey)
 			
 			// Set callback to update player when new ads arrive
-			adFetcher.SetOnAdsUpdated(func(adResponse *models.AdDeliveryResponse) {
-				if adPlayer != nil {
-					adPlayer.UpdateAds(adResponse)
+			if adMerger != nil {
+				adMerger.SetOnAdsUpdated(func(adResponse *models.AdDeliveryResponse) {
+					if adPlayer != nil {
+						adPlayer.UpdateAds(adResponse)
+					}
+				})
+				adFetcher.SetOnAdsUpdated(adMerger.UpdatePrimary)
+			} else {
+				adFetcher.SetOnAdsUpdated(func(adResponse *models.AdDeliveryResponse) {
+					if adPlayer != nil {
+						adPlayer.UpdateAds(adResponse)
+					}
+				})
+			}
+
+			// Handle remote commands pushed by the server
+			adFetcher.SetOnCommand(func(cmd *client.CommandPayload) {
+				if adPlayer == nil {
+					return
+				}
+				switch cmd.Command {
+				case "pause":
+					adPlayer.Pause()
+				case "resume":
+					adPlayer.Resume()
+				default:
+					log.Printf("[WARN] Unsupported remote command: %s", cmd.Command)
 				}
 			})
 			
 			// Load initial ads into player
 			if storedAds, err := adFetcher.LoadAdsFromStorage(); err == nil && len(storedAds.Ads) > 0 {
-				adPlayer.UpdateAds(storedAds)
+				if adMerger != nil {
+					adMerger.UpdatePrimary(storedAds)
+				} else {
+					adPlayer.UpdateAds(storedAds)
+				}
+			}
+			
+			// Start fetching only once every handler is registered, so nothing pushed on connect is lost
+			adFetcher.Start()
+			fmt.Printf("   [OK] Ad fetcher started (interval: %d seconds)\n", screenConfig.AdFetchInterval)
+			if screenConfig.PushEnabled {
+				fmt.Println("   [INFO] Server push enabled (polling used as fallback)")
 			}
 			
 			// Start player
@@ -282,6 +430,10 @@ func main() {
 					fmt.Printf("   [INFO] Player ready with %d ads in playlist\n", adPlayer.GetPlaylist().GetCount())
 				}
 			}
+			if adMerger != nil {
+				adMerger.Start()
+				fmt.Println("   [OK] Ad sources started, merging them with the ad server's playlist")
+			}
 		}
 	}
 
@@ -289,10 +441,13 @@ func main() {
 	// This allows manual testing with locally created ads
 	if adPlayer == nil {
 		adStorage := ads.NewStorage(configDir)
+		adStorage.SetPlaylistKey(playlistKey)
 		if storedAds, err := adStorage.LoadAds(); err == nil && len(storedAds.Ads) > 0 {
 			fmt.Println()
 			fmt.Println("Starting ad player (manual ads detected)...")
 			adPlayer = player.NewPlayer(adStorage, screenConfig)
+			adPlayer.SetEventQueue(eventQueue)
+			adPlayer.SetTransport(httpTransport)
 			
 			// Load ads into player
 			adPlayer.UpdateAds(storedAds)
@@ -312,10 +467,13 @@ func main() {
 	// This allows manual testing with locally created ads
 	if adPlayer == nil {
 		adStorage := ads.NewStorage(configDir)
+		adStorage.SetPlaylistKey(playlistKey)
 		if storedAds, err := adStorage.LoadAds(); err == nil && len(storedAds.Ads) > 0 {
 			fmt.Println()
 			fmt.Println("Starting ad player (manual ads detected)...")
 			adPlayer = player.NewPlayer(adStorage, screenConfig)
+			adPlayer.SetEventQueue(eventQueue)
+			adPlayer.SetTransport(httpTransport)
 			
 			// Load ads into player
 			adPlayer.UpdateAds(storedAds)
@@ -334,6 +492,7 @@ func main() {
 	fmt.Println()
 	fmt.Println("[OK] Screen system initialized successfully!")
 	fmt.Println()
+	exportSnapshot(configDir)
 	
 	// If heartbeat is running, show status and wait for interrupt
 	if heartbeatScheduler != nil {
@@ -359,12 +518,20 @@ func main() {
 					adPlayer.Stop()
 				}
 				heartbeatScheduler.Stop()
+				stopHealthChecks()
+				if adMerger != nil {
+					adMerger.Stop()
+				}
 				if adFetcher != nil {
 					adFetcher.Stop()
 				}
+				if popUploader != nil {
+					popUploader.Stop()
+				}
 				fmt.Println("[OK] Shutdown complete")
 				return
 			case <-statusTicker.C:
+				exportSnapshot(configDir)
 				stats := heartbeatScheduler.GetStats()
 				status := stats["status"].(string)
 				connected := stats["connected"].(bool)
@@ -378,7 +545,24 @@ func main() {
 				if lastSent, ok := stats["timeSinceLastSent"]; ok {
 					fmt.Printf(" (last sent: %s ago)", lastSent)
 				}
+				if len(serverEndpoints) > 1 {
+					fmt.Printf(" via %s", stats["activeEndpoint"])
+				}
 				fmt.Println()
+				
+				if adPlayer != nil {
+					printPlayerStatus(adPlayer)
+				}
+				if adMerger != nil {
+					for _, source := range adMerger.GetStats()["sources"].([]map[string]interface{}) {
+						fmt.Printf("[SOURCES] %s (%s): %d ads, weight %d, priority %d", source["name"], source["type"],
+							source["adsCount"], source["weight"], source["priority"])
+						if lastError, ok := source["lastError"]; ok {
+							fmt.Printf(" | last error: %s", lastError)
+						}
+						fmt.Println()
+					}
+				}
 			}
 		}
 	} else {
@@ -409,14 +593,8 @@ func main() {
 					fmt.Println("[OK] Shutdown complete")
 					return
 				case <-statusTicker.C:
-					stats := adPlayer.GetStats()
-					currentAd := adPlayer.GetCurrentAd()
-					if currentAd != nil {
-						fmt.Printf("[PLAYER] Currently playing: %s (type: %s) | Total played: %d\n", 
-							currentAd.ID, currentAd.Type, stats.TotalAdsPlayed)
-					} else {
-						fmt.Printf("[PLAYER] Waiting for ads... | Total played: %d\n", stats.TotalAdsPlayed)
-					}
+					exportSnapshot(configDir)
+					printPlayerStatus(adPlayer)
 				}
 			}
 		} else {
@@ -452,3 +630,104 @@ func main() {
 	}
 }
 
+// exportSnapshot refreshes the database copy that test-ads reads while the screen holds the database
+func exportSnapshot(configDir string) {
+	if err := db.ExportSnapshot(configDir); err != nil {
+		log.Printf("[WARN] Failed to export database snapshot: %v", err)
+	}
+}
+
+// printPlayerStatus prints what the player is doing, its media cache, downloads, prefetch and offline state
+func printPlayerStatus(adPlayer *player.Player) {
+	stats := adPlayer.GetStats()
+	currentAd := adPlayer.GetCurrentAd()
+	if currentAd != nil {
+		fmt.Printf("[PLAYER] Currently playing: %s (type: %s) | Total played: %d\n", 
+			currentAd.ID, currentAd.Type, stats.TotalAdsPlayed)
+	} else {
+		fmt.Printf("[PLAYER] Waiting for ads... | Total played: %d\n", stats.TotalAdsPlayed)
+	}
+	if cacheStats := adPlayer.GetCacheStats(); !cacheStats.LastCheck.IsZero() {
+		fmt.Printf("[CACHE] %d files, %.1f/%.1f MB | Evicted: %d\n", cacheStats.Files, 
+			float64(cacheStats.Bytes)/(1<<20), float64(cacheStats.Quota)/(1<<20), cacheStats.Evicted)
+	}
+	downloads := adPlayer.GetDownloadStatus()
+	if len(downloads.Queued) > 0 || len(downloads.InProgress) > 0 || len(downloads.Failed) > 0 {
+		fmt.Printf("[DOWNLOADS] Queued: %d | In progress: %d | Completed: %d | Failed: %d\n", 
+			len(downloads.Queued), len(downloads.InProgress), downloads.Completed, len(downloads.Failed))
+		for _, download := range downloads.InProgress {
+			if download.Total > 0 {
+				fmt.Printf("   - %s: %.1f/%.1f MB from %s\n", download.AdID, 
+					float64(download.Downloaded)/(1<<20), float64(download.Total)/(1<<20), download.Host)
+			} else {
+				fmt.Printf("   - %s: %.1f MB from %s\n", download.AdID, float64(download.Downloaded)/(1<<20), download.Host)
+			}
+		}
+		if n := len(downloads.Failed); n > 0 {
+			failed := downloads.Failed[n-1]
+			fmt.Printf("   Last failure: %s at %s: %s\n", failed.AdID, failed.FinishedAt.Format("15:04:05"), failed.Error)
+		}
+	}
+	if stats.PrefetchTotal > 0 {
+		fmt.Printf("[PLAYER] Prefetching next playlist: %d/%d ads ready (%v)\n", 
+			stats.PrefetchReady, stats.PrefetchTotal, time.Since(stats.PrefetchStarted).Round(time.Second))
+	}
+	if stats.OfflineExpiredAds > 0 {
+		fmt.Printf("[PLAYER] Offline for %v: %d ads expired | Fallback playlist: %v\n", 
+			stats.OfflineFor.Round(time.Second), stats.OfflineExpiredAds, stats.UsingFallback)
+	}
+}
+
+// newAdMerger builds a merger for the ad sources configured besides the ad server
+// Returns nil if there are none, so the ad server's playlist goes to the player directly
+// A pinned playlist key applies to every source; sources that can't be set up are logged and left out
+func newAdMerger(screenConfig *models.ScreenConfig, adsDir, screenID, passkey string, httpTransport http.RoundTripper, playlistKey ed25519.PublicKey) *ads.Merger {
+	extra := false
+	for _, sourceConfig := range screenConfig.AdSources {
+		if sourceConfig.Type != models.AdSourceAdServer {
+			extra = true
+		}
+	}
+	if !extra {
+		return nil
+	}
+
+	merger := ads.NewMerger(screenConfig.AdFetchInterval)
+	for _, sourceConfig := range screenConfig.AdSources {
+		if sourceConfig.Type == models.AdSourceAdServer {
+			if err := merger.AddSource(sourceConfig, nil); err != nil {
+				log.Printf("[WARN] Ad source disabled: %v", err)
+			}
+			continue
+		}
+
+		// Secondary servers use this screen's credentials unless they have their own
+		var serverClient *client.Client
+		sourceScreenID := screenID
+		if sourceConfig.Type == models.AdSourceServer {
+			if sourceConfig.ScreenID != "" {
+				sourceScreenID = sourceConfig.ScreenID
+			}
+			sourcePasskey := passkey
+			if sourceConfig.Passkey != "" {
+				sourcePasskey = sourceConfig.Passkey
+			}
+			serverClient = client.NewClient(sourceConfig.URL, sourceScreenID, sourcePasskey)
+			serverClient.SetRetryPolicy(retry.FromConfig(screenConfig))
+			serverClient.SetTransport(httpTransport, transport.RequestTimeout(screenConfig.Transport))
+			serverClient.SetSigning(screenConfig.AuthMode == models.AuthModeHMAC)
+			serverClient.SetMaxResponseSize(screenConfig.MaxAdResponseSize)
+		}
+
+		// A bad source is left out rather than disabling the others
+		source, err := ads.NewSource(sourceConfig, adsDir, serverClient, sourceScreenID, playlistKey)
+		if err == nil {
+			err = merger.AddSource(sourceConfig, source)
+		}
+		if err != nil {
+			log.Printf("[WARN] Ad source disabled: %v", err)
+			fmt.Printf("   [WARN] Ad source %s disabled: %v\n", sourceConfig.Name, err)
+		}
+	}
+	return merger
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"mnemoCast-client/internal/ads"
+	"mnemoCast-client/internal/db"
+	"mnemoCast-client/internal/heartbeat"
 	"mnemoCast-client/internal/models"
 	"os"
 	"path/filepath"
@@ -21,6 +24,10 @@ func main() {
 		fmt.Println("  add-text       - Add a text ad (requires: <ad-id> <text-content> [title] [duration])")
 		fmt.Println("  list           - List current ads")
 		fmt.Println("  clear          - Clear all ads")
+		fmt.Println("  history        - List stored playlist snapshots (0 = newest)")
+		fmt.Println("  rollback       - Restore a stored playlist and pin it (requires: <n> from history)")
+		fmt.Println("  unpin          - Let the server replace a rolled-back playlist again")
+		fmt.Println("  heartbeats     - List recent heartbeat results (optional: [count], default 20)")
 		fmt.Println()
 		fmt.Println("Examples:")
 		fmt.Println("  go run cmd/test-ads/main.go create-sample")
@@ -29,6 +36,13 @@ func main() {
 		fmt.Println("  go run cmd/test-ads/main.go add-text ad-003 'Hello World' 'Text Ad' 15")
 		fmt.Println("  go run cmd/test-ads/main.go list")
 		fmt.Println("  go run cmd/test-ads/main.go clear")
+		fmt.Println("  go run cmd/test-ads/main.go history")
+		fmt.Println("  go run cmd/test-ads/main.go rollback 1")
+		fmt.Println("  go run cmd/test-ads/main.go unpin")
+		fmt.Println("  go run cmd/test-ads/main.go heartbeats 5")
+		fmt.Println()
+		fmt.Println("While the screen is running, list, history and heartbeats read the snapshot it exports every 30 seconds;")
+		fmt.Println("stop the screen to change the playlist.")
 		os.Exit(1)
 	}
 
@@ -38,9 +52,16 @@ func main() {
 		log.Fatalf("Failed to get home directory: %v", err)
 	}
 	configDir := filepath.Join(homeDir, ".mnemocast")
-	storage := ads.NewStorage(configDir)
-
 	command := os.Args[1]
+	defer db.CloseAll()
+
+	// The running screen holds the database; read-only commands use the copy it exports
+	switch command {
+	case "list", "history", "heartbeats":
+		openForReading(configDir)
+	}
+
+	storage := ads.NewStorage(configDir)
 
 	switch command {
 	case "create-sample":
@@ -64,6 +85,21 @@ func main() {
 		listAds(storage)
 	case "clear":
 		clearAds(storage)
+	case "history":
+		listHistory(storage)
+	case "rollback":
+		if len(os.Args) < 3 {
+			log.Fatal("Usage: rollback <n>")
+		}
+		index := getOptionalIntArg(os.Args, 2, -1)
+		if index < 0 {
+			log.Fatalf("Invalid snapshot index: %s", os.Args[2])
+		}
+		rollbackAds(storage, index)
+	case "unpin":
+		unpinAds(storage)
+	case "heartbeats":
+		listHeartbeats(configDir, getOptionalIntArg(os.Args, 2, 20))
 	default:
 		log.Fatalf("Unknown command: %s", command)
 	}
@@ -364,6 +400,92 @@ func clearAds(storage *ads.Storage) {
 	fmt.Println("[OK] Cleared all ads")
 }
 
+func listHistory(storage *ads.Storage) {
+	entries, err := storage.ListHistory()
+	if err != nil {
+		log.Fatalf("Failed to read playlist history: %v", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("[INFO] No playlist history found")
+		return
+	}
+
+	fmt.Printf("Found %d playlist snapshots (newest first):\n\n", len(entries))
+	if pin := storage.LoadPin(); pin != nil {
+		fmt.Printf("[INFO] Current playlist pinned by a rollback at %s\n\n", pin.PinnedAt.Format(time.RFC3339))
+	}
+	for _, entry := range entries {
+		playlistID := entry.PlaylistID
+		if playlistID == "" {
+			playlistID = "(none)"
+		}
+		fmt.Printf("%d. Playlist: %s\n", entry.Index, playlistID)
+		fmt.Printf("   Ads: %d\n", entry.AdsCount)
+		fmt.Printf("   Updated: %s\n", entry.UpdatedAt.Format(time.RFC3339))
+		fmt.Printf("   Saved: %s\n", entry.SavedAt.Format(time.RFC3339))
+		fmt.Println()
+	}
+}
+
+func rollbackAds(storage *ads.Storage, index int) {
+	restored, err := storage.Rollback(index)
+	if err != nil {
+		log.Fatalf("Failed to roll back playlist: %v", err)
+	}
+
+	fmt.Printf("[OK] Restored and pinned playlist snapshot %d (%d ads)\n", index, len(restored.Ads))
+	fmt.Println("   Restart the screen to play it; it stays in place of the server's playlist until 'unpin'")
+}
+
+func unpinAds(storage *ads.Storage) {
+	if storage.LoadPin() == nil {
+		fmt.Println("[INFO] Playlist is not pinned")
+		return
+	}
+	if err := storage.Unpin(); err != nil {
+		log.Fatalf("Failed to unpin playlist: %v", err)
+	}
+
+	fmt.Println("[OK] Playlist unpinned; the next successful fetch from the server replaces it")
+}
+
+func listHeartbeats(configDir string, count int) {
+	history, err := heartbeat.NewHistory(configDir, 0)
+	if err != nil {
+		log.Fatalf("Failed to open heartbeat history: %v", err)
+	}
+	records, err := history.Recent(count)
+	if err != nil {
+		log.Fatalf("Failed to read heartbeat history: %v", err)
+	}
+	if len(records) == 0 {
+		fmt.Println("[INFO] No heartbeats recorded")
+		return
+	}
+
+	fmt.Printf("Last %d heartbeats (newest first):\n\n", len(records))
+	for _, record := range records {
+		fmt.Printf("%s  %-10s %6d ms", record.At.Format(time.RFC3339), record.Status, record.DurationMs)
+		if record.Error != "" {
+			fmt.Printf("  %s", record.Error)
+		}
+		fmt.Println()
+	}
+}
+
+// openForReading switches to the screen's database snapshot if the screen holds the database
+func openForReading(configDir string) {
+	if _, err := db.Open(configDir); !errors.Is(err, db.ErrInUse) {
+		return
+	}
+
+	exported, err := db.OpenSnapshot(configDir)
+	if err != nil {
+		log.Fatalf("The screen is running and holds the database, and hasn't exported a snapshot yet: %v", err)
+	}
+	fmt.Printf("[INFO] The screen is running; showing its snapshot from %s\n\n", exported.Format(time.RFC3339))
+}
+
 func getOptionalArg(args []string, index int, defaultValue string) string {
 	if index < len(args) {
 		return args[index]
@@ -3,3 +3,7 @@ module mnemoCast-client
 go 1.22.0
 
 toolchain go1.22.2
+
+require go.etcd.io/bbolt v1.3.11
+
+require golang.org/x/sys v0.4.0 // indirect
@@ -0,0 +1,95 @@
+package ads
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mnemoCast-client/internal/models"
+)
+
+// PlaylistDiff describes how a playlist changed between two deliveries
+// Ads are matched by ID
+type PlaylistDiff struct {
+	Added     []models.Ad
+	Removed   []models.Ad
+	Changed   []AdChange
+	Unchanged int
+}
+
+// AdChange is an ad present in both playlists whose content or metadata changed
+type AdChange struct {
+	Old          models.Ad
+	New          models.Ad
+	MediaChanged bool // Content URL, type or checksum changed, so cached media is stale
+}
+
+// DiffPlaylists compares two playlists; either may be nil (treated as empty)
+func DiffPlaylists(previous, current *models.AdDeliveryResponse) *PlaylistDiff {
+	diff := &PlaylistDiff{}
+
+	oldAds := make(map[string]models.Ad)
+	if previous != nil {
+		for _, ad := range previous.Ads {
+			oldAds[ad.ID] = ad
+		}
+	}
+
+	seen := make(map[string]bool)
+	if current != nil {
+		for _, ad := range current.Ads {
+			seen[ad.ID] = true
+			old, existed := oldAds[ad.ID]
+			switch {
+			case !existed:
+				diff.Added = append(diff.Added, ad)
+			case sameAd(old, ad):
+				diff.Unchanged++
+			default:
+				diff.Changed = append(diff.Changed, AdChange{
+					Old:          old,
+					New:          ad,
+					MediaChanged: old.ContentURL != ad.ContentURL || old.Type != ad.Type || old.SHA256() != ad.SHA256(),
+				})
+			}
+		}
+	}
+
+	if previous != nil {
+		for _, ad := range previous.Ads {
+			if !seen[ad.ID] {
+				diff.Removed = append(diff.Removed, ad)
+			}
+		}
+	}
+
+	return diff
+}
+
+// IsEmpty reports whether no ad was added, removed or changed
+func (d *PlaylistDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// NeedsMedia returns the ads whose media must be (re)downloaded: added ads and ads with new content
+func (d *PlaylistDiff) NeedsMedia() []models.Ad {
+	var ads []models.Ad
+	ads = append(ads, d.Added...)
+	for _, change := range d.Changed {
+		if change.MediaChanged {
+			ads = append(ads, change.New)
+		}
+	}
+	return ads
+}
+
+// String summarises the diff, e.g. "+2 -1 ~3 =10"
+func (d *PlaylistDiff) String() string {
+	return fmt.Sprintf("+%d -%d ~%d =%d", len(d.Added), len(d.Removed), len(d.Changed), d.Unchanged)
+}
+
+// sameAd reports whether two versions of an ad are identical in every field
+func sameAd(a, b models.Ad) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
@@ -2,10 +2,15 @@ package ads
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"mnemoCast-client/internal/cadence"
 	"mnemoCast-client/internal/client"
 	"mnemoCast-client/internal/models"
+	"mnemoCast-client/internal/retry"
 	"sync"
 	"time"
 )
@@ -15,8 +20,7 @@ type Fetcher struct {
 	client        *client.Client
 	screenID      string
 	interval      time.Duration
-	retryAttempts int
-	retryDelay    time.Duration
+	cadence       *cadence.Cadence // Effective interval, adjusted by server hints, playlist expiry and Retry-After
 	storage       *Storage
 
 	ctx      context.Context
@@ -26,39 +30,96 @@ type Fetcher struct {
 	lastAds  *models.AdDeliveryResponse
 	lastFetch time.Time
 	lastError error
+	notModifiedCount int
+	authFailed       bool // Last fetch was rejected as unauthorized or unknown screen
+
+	// Pinned playlist signing key; when set, unsigned or badly signed playlists are rejected
+	playlistKey       ed25519.PublicKey
+	playlistsRejected int
+	lastRejection     error
+
+	// Ads dropped by validation from the last fetched playlist
+	rejectedAds      []models.AdRejection
+	rejectedAdsTotal int
+	lastReport       string // Rejections last reported to the server, to avoid repeating a report
+
+	// Server push channel; polling is suspended while it is connected, unless the playlist
+	// has expired or the server asked for an interval
+	pushEnabled   bool
+	pushConnected bool
+	lastEventID   string
+	fetchRequests chan bool // Immediate fetch requests; true forces a full reload
 	
 	// Callback for ad updates
 	onAdsUpdated func(*models.AdDeliveryResponse)
+	// Subscribers notified with the diff of each playlist change
+	subscribers []func(*models.AdDeliveryResponse, *PlaylistDiff)
+	// Callback for remote commands received over the push channel
+	onCommand func(*client.CommandPayload)
 }
 
 // NewFetcher creates a new ad fetcher
+// Retries are governed by the retry policy configured on adClient
 func NewFetcher(
 	adClient *client.Client,
 	screenID string,
 	configDir string,
 	intervalSeconds int,
-	retryAttempts int,
-	retryDelaySeconds int,
 ) *Fetcher {
 	ctx, cancel := context.WithCancel(context.Background())
+	storage := NewStorage(configDir)
 
 	return &Fetcher{
 		client:        adClient,
 		screenID:      screenID,
 		interval:      time.Duration(intervalSeconds) * time.Second,
-		retryAttempts: retryAttempts,
-		retryDelay:    time.Duration(retryDelaySeconds) * time.Second,
-		storage:       NewStorage(configDir),
+		cadence:       cadence.New(time.Duration(intervalSeconds)*time.Second, 0, 0),
+		storage:       storage,
+		lastFetch:     storage.LoadConfirmed(), // Survives restarts for the offline policy
 		ctx:           ctx,
 		cancel:        cancel,
+		fetchRequests: make(chan bool, 1),
 	}
 }
 
+// SetIntervalBounds limits the fetch interval the server may request; must be called before Start
+// Values <= 0 use the defaults
+func (f *Fetcher) SetIntervalBounds(min, max time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cadence = cadence.New(f.interval, min, max)
+}
+
+// SetPushEnabled enables the server push channel; must be called before Start
+func (f *Fetcher) SetPushEnabled(enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pushEnabled = enabled
+}
+
+// SetPlaylistKey pins the Ed25519 key playlists must be signed with; must be called before Start
+// The key also applies to the playlist stored on disk
+func (f *Fetcher) SetPlaylistKey(key ed25519.PublicKey) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.playlistKey = key
+	f.storage.SetPlaylistKey(key)
+}
+
 // Start starts the ad fetcher in a background goroutine
 func (f *Fetcher) Start() {
 	f.wg.Add(1)
 	go f.run()
 	log.Printf("[ADS] Ad fetcher started (interval: %v)", f.interval)
+
+	f.mu.RLock()
+	pushEnabled := f.pushEnabled
+	f.mu.RUnlock()
+	if pushEnabled {
+		f.wg.Add(1)
+		go f.runPush()
+		log.Printf("[ADS] Push channel enabled, polling is used while it is disconnected or the playlist expires")
+	}
 }
 
 // Stop gracefully stops the ad fetcher
@@ -75,130 +136,388 @@ func (f *Fetcher) run() {
 
 	// Fetch ads immediately on start
 	log.Printf("[%s] [INIT] Fetching initial ads...", time.Now().Format("15:04:05.000"))
-	f.fetchAds()
+	f.fetchAds(false)
 
-	// Create ticker for periodic ad fetching
-	ticker := time.NewTicker(f.interval)
-	defer ticker.Stop()
+	// The wait is recomputed after every fetch, since the server may change it
+	timer := time.NewTimer(f.cadence.Next())
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-f.ctx.Done():
 			log.Printf("[%s] [SHUTDOWN] Ad fetcher stopping...", time.Now().Format("15:04:05.000"))
 			return
-		case <-ticker.C:
+		case reload := <-f.fetchRequests:
+			log.Printf("[%s] [PUSH] Fetch requested by server (reload: %v), fetching ads...", 
+				time.Now().Format("15:04:05.000"), reload)
+			f.fetchAds(reload)
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(f.cadence.Next())
+		case <-timer.C:
+			if f.IsPushConnected() && !f.pollDue() {
+				// The idle watchdog drops a silent stream, so a connected one has heard from the server recently
+				f.confirmPush()
+				timer.Reset(f.cadence.Next())
+				continue
+			}
 			tickTime := time.Now()
 			log.Printf("[%s] [TIMER] Ad fetch interval reached (every %v), fetching ads...", 
-				tickTime.Format("15:04:05.000"), f.interval)
-			f.fetchAds()
+				tickTime.Format("15:04:05.000"), f.cadence.Interval())
+			f.fetchAds(false)
+			timer.Reset(f.cadence.Next())
 		}
 	}
 }
 
-// fetchAds fetches ads from the server with retry logic
-func (f *Fetcher) fetchAds() {
+// fetchAds fetches ads from the server
+// Transient failures are retried inside the client using the shared retry policy.
+// force skips the conditional request so the full playlist is always delivered.
+func (f *Fetcher) fetchAds(force bool) {
 	startTime := time.Now()
 	log.Printf("[%s] [FETCH] Starting ad fetch cycle for screen: %s", startTime.Format("15:04:05.000"), f.screenID)
 
-	var lastErr error
+	// Only send validators while the playlist they describe is still on disk
+	var validators *models.CacheValidators
+	if !force {
+		validators = f.storage.LoadValidators()
+	}
 
-	// Retry logic
-	for attempt := 0; attempt <= f.retryAttempts; attempt++ {
-		if attempt > 0 {
-			delay := f.retryDelay * time.Duration(attempt)
-			retryTime := time.Now()
-			log.Printf("[%s] [RETRY] Retrying ad fetch (attempt %d/%d) after %v...", 
-				retryTime.Format("15:04:05.000"), attempt, f.retryAttempts, delay)
-			time.Sleep(delay)
+	ads, newValidators, err := f.client.GetAdsConditional(f.ctx, f.screenID, validators)
+	if errors.Is(err, client.ErrNotModified) {
+		f.handleNotModified(startTime)
+		return
+	}
+	if err != nil {
+		if f.ctx.Err() != nil {
+			log.Printf("[%s] [SHUTDOWN] Ad fetch cycle cancelled", time.Now().Format("15:04:05.000"))
+			return
 		}
 
-		attemptTime := time.Now()
-		log.Printf("[%s] [ATTEMPT] Attempting ad fetch (attempt %d/%d)...", 
-			attemptTime.Format("15:04:05.000"), attempt+1, f.retryAttempts+1)
+		failureTime := time.Now()
+		totalDuration := failureTime.Sub(startTime)
 
-		ads, err := f.client.GetAds(f.screenID)
-		if err == nil {
-			// Success
-			successTime := time.Now()
-			totalDuration := successTime.Sub(startTime)
+		authFailed := client.IsAuthError(err) || client.IsNotFoundError(err)
+		if authFailed {
+			log.Printf("[%s] [AUTH] Ad server rejected the screen's credentials, keeping current playlist", 
+				failureTime.Format("15:04:05.000"))
+		}
 
-			f.mu.Lock()
-			f.lastAds = ads
-			f.lastFetch = time.Now()
-			f.lastError = nil
-			f.mu.Unlock()
+		f.mu.Lock()
+		f.lastError = err
+		f.authFailed = authFailed
+		f.mu.Unlock()
+
+		if wait := retry.RetryAfter(err); wait > 0 {
+			f.cadence.Defer(wait)
+			log.Printf("[%s] [FETCH] Server asked to retry after %v, delaying next fetch", 
+				failureTime.Format("15:04:05.000"), wait)
+		}
+
+		log.Printf("[%s] [ERROR] Ad fetch cycle failed | Total duration: %v | Error: %v", 
+			failureTime.Format("15:04:05.000"), totalDuration, err)
+		return
+	}
+
+	ads, err = f.verifyPlaylist(ads)
+	if err != nil {
+		f.rejectPlaylist(err)
+		return
+	}
+
+	result := ValidatePlaylist(ads)
+	f.applyValidation(ads, result)
+	if len(ads.Ads) == 0 && len(result.Rejected) > 0 {
+		// An empty screen is worse than the previous playlist (or the fallback)
+		f.rejectPlaylist(fmt.Errorf("all %d ads failed validation", len(result.Rejected)))
+		return
+	}
+
+	if pin := f.storage.LoadPin(); pin != nil {
+		f.holdPinned(pin, ads)
+		return
+	}
+
+	// Success
+	successTime := time.Now()
+	totalDuration := successTime.Sub(startTime)
 
-			// Save ads to filesystem
-			if err := f.storage.SaveAds(ads); err != nil {
-				log.Printf("[%s] [WARN] Failed to save ads to filesystem: %v", successTime.Format("15:04:05.000"), err)
-			} else {
-				log.Printf("[%s] [OK] Ads saved to filesystem: %s", successTime.Format("15:04:05.000"), f.storage.GetAdsDir())
+	f.mu.Lock()
+	previous := f.lastAds
+	f.lastAds = ads
+	f.lastFetch = time.Now()
+	f.lastError = nil
+	f.authFailed = false
+	f.mu.Unlock()
+
+	if previous == nil {
+		// First fetch since start: compare against what the player loaded from storage
+		previous, _ = f.storage.LoadAds()
+	}
+	diff := DiffPlaylists(previous, ads)
+	f.applyHints(ads)
+	if !ads.ExpiresAt.IsZero() {
+		log.Printf("[%s] [FETCH] Playlist expires at %s", 
+			successTime.Format("15:04:05.000"), ads.ExpiresAt.Format(time.RFC3339))
+	}
+
+	// Save ads to storage
+	if err := f.storage.SaveAds(ads); err != nil {
+		log.Printf("[%s] [WARN] Failed to save ads to storage: %v", successTime.Format("15:04:05.000"), err)
+	} else {
+		log.Printf("[%s] [OK] Ads saved to storage", successTime.Format("15:04:05.000"))
+		f.saveConfirmed(successTime)
+		if err := f.storage.SaveValidators(newValidators); err != nil {
+			log.Printf("[%s] [WARN] Failed to save cache validators: %v", successTime.Format("15:04:05.000"), err)
+		}
+	}
+	
+	// Only notify when an ad actually changed, so playback isn't disturbed by re-deliveries
+	if diff.IsEmpty() {
+		log.Printf("[%s] [DIFF] Playlist content unchanged (%s)", successTime.Format("15:04:05.000"), diff)
+	} else {
+		log.Printf("[%s] [DIFF] Playlist changed: %s", successTime.Format("15:04:05.000"), diff)
+		f.notify(ads, diff)
+	}
+
+	log.Printf("[%s] [OK] Ad fetch completed successfully | Total duration: %v | Ads received: %d", 
+		successTime.Format("15:04:05.000"), totalDuration, len(ads.Ads))
+
+	// Log ad details and JSON response
+	if len(ads.Ads) > 0 {
+		log.Printf("[%s] [INFO] Received %d ads for display", successTime.Format("15:04:05.000"), len(ads.Ads))
+		
+		// Log full JSON response
+		jsonData, err := json.MarshalIndent(ads, "", "  ")
+		if err != nil {
+			log.Printf("[%s] [WARN] Failed to marshal ads to JSON: %v", successTime.Format("15:04:05.000"), err)
+		} else {
+			log.Printf("[%s] [RESPONSE] Ads JSON Response:\n%s", successTime.Format("15:04:05.000"), string(jsonData))
+		}
+		
+		// Log individual ad summary
+		for i, ad := range ads.Ads {
+			log.Printf("[%s] [INFO] Ad %d: ID=%s, Type=%s, URL=%s", 
+				successTime.Format("15:04:05.000"), i+1, ad.ID, ad.Type, ad.ContentURL)
+		}
+	} else {
+		log.Printf("[%s] [INFO] No ads available for display", successTime.Format("15:04:05.000"))
+		
+		// Log JSON response even when no ads
+		jsonData, err := json.MarshalIndent(ads, "", "  ")
+		if err != nil {
+			log.Printf("[%s] [WARN] Failed to marshal ads to JSON: %v", successTime.Format("15:04:05.000"), err)
+		} else {
+			log.Printf("[%s] [RESPONSE] Ads JSON Response:\n%s", successTime.Format("15:04:05.000"), string(jsonData))
+		}
+	}
+}
+
+// rejectPlaylist records a fetched playlist that won't be played; the current one stays in effect
+func (f *Fetcher) rejectPlaylist(err error) {
+	f.mu.Lock()
+	f.lastError = err
+	f.lastRejection = err
+	f.playlistsRejected++
+	f.authFailed = false
+	f.mu.Unlock()
+
+	log.Printf("[%s] [ERROR] Playlist rejected, keeping current playlist: %v", 
+		time.Now().Format("15:04:05.000"), err)
+}
+
+// holdPinned records a fetch made while a rolled-back playlist is pinned
+// The server was reached, which confirms the screen for the offline policy, but its playlist isn't applied
+func (f *Fetcher) holdPinned(pin *PlaylistPin, ads *models.AdDeliveryResponse) {
+	now := time.Now()
+	f.mu.Lock()
+	f.lastFetch = now
+	f.lastError = nil
+	f.authFailed = false
+	f.mu.Unlock()
+
+	f.saveConfirmed(now)
+	log.Printf("[%s] [FETCH] Playlist pinned by a rollback at %s, ignoring server playlist %q (%d ads); run test-ads unpin to resume updates", 
+		now.Format("15:04:05.000"), pin.PinnedAt.Format(time.RFC3339), ads.PlaylistID, len(ads.Ads))
+}
+
+// pollDue reports whether the poll timer should fetch even though the push channel is connected:
+// the playlist has expired, or the server set an interval with nextFetchAfter
+func (f *Fetcher) pollDue() bool {
+	if f.cadence.Hinted() {
+		return true
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.lastAds != nil && !f.lastAds.ExpiresAt.IsZero() && !time.Now().Before(f.lastAds.ExpiresAt)
+}
+
+// confirmPush counts the live push channel as a confirmation of the playlist for the offline policy
+// The server pushes every change, so a screen that stays connected is never out of date
+func (f *Fetcher) confirmPush() {
+	now := time.Now()
+	f.mu.Lock()
+	f.lastFetch = now
+	f.mu.Unlock()
+	f.saveConfirmed(now)
+}
+
+// saveConfirmed persists when the server last confirmed the playlist
+func (f *Fetcher) saveConfirmed(at time.Time) {
+	if err := f.storage.SaveConfirmed(at); err != nil {
+		log.Printf("[%s] [WARN] Failed to save confirmation time: %v", time.Now().Format("15:04:05.000"), err)
+	}
+}
+
+// applyValidation logs and records the ads validation removed, and reports them to the server
+func (f *Fetcher) applyValidation(ads *models.AdDeliveryResponse, result *ValidationResult) {
+	timestamp := time.Now().Format("15:04:05.000")
+	for _, rejection := range result.Rejected {
+		log.Printf("[%s] [VALIDATE] [WARN] Rejected ad %q (#%d): %s", timestamp, rejection.AdID, rejection.Index+1, rejection.Reason)
+	}
+	if result.Normalized > 0 {
+		log.Printf("[%s] [VALIDATE] Normalized %d ads", timestamp, result.Normalized)
+	}
+
+	f.mu.Lock()
+	f.rejectedAds = result.Rejected
+	f.rejectedAdsTotal += len(result.Rejected)
+	f.mu.Unlock()
+
+	if len(result.Rejected) > 0 {
+		f.reportRejections(ads.PlaylistID, result.Rejected)
+	}
+}
+
+// reportRejections sends rejected ads to the server in the background
+// The same set of rejections is reported only once, even if the playlist is delivered again
+func (f *Fetcher) reportRejections(playlistID string, rejected []models.AdRejection) {
+	key := playlistID
+	for _, rejection := range rejected {
+		key += "\x00" + rejection.AdID + "\x00" + rejection.Reason
+	}
+
+	f.mu.Lock()
+	if key == f.lastReport {
+		f.mu.Unlock()
+		return
+	}
+	f.lastReport = key
+	f.mu.Unlock()
+
+	report := &models.AdRejectionReport{
+		PlaylistID: playlistID,
+		Rejected:   rejected,
+		ReportedAt: time.Now(),
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		if err := f.client.ReportRejectedAds(f.ctx, f.screenID, report); err != nil {
+			if f.ctx.Err() == nil {
+				log.Printf("[%s] [VALIDATE] [WARN] Failed to report rejected ads: %v", time.Now().Format("15:04:05.000"), err)
 			}
-			
-			// Call callback if set
-			f.mu.RLock()
-			callback := f.onAdsUpdated
-			f.mu.RUnlock()
-			if callback != nil {
-				callback(ads)
+			// Try again with the next delivery
+			f.mu.Lock()
+			if f.lastReport == key {
+				f.lastReport = ""
 			}
+			f.mu.Unlock()
+			return
+		}
+		log.Printf("[%s] [VALIDATE] Reported %d rejected ads to the server", time.Now().Format("15:04:05.000"), len(rejected))
+	}()
+}
 
-			if attempt > 0 {
-				log.Printf("[%s] [OK] Ad fetch succeeded after %d retries | Total duration: %v | Ads received: %d", 
-					successTime.Format("15:04:05.000"), attempt, totalDuration, len(ads.Ads))
-			} else {
-				log.Printf("[%s] [OK] Ad fetch completed successfully | Total duration: %v | Ads received: %d", 
-					successTime.Format("15:04:05.000"), totalDuration, len(ads.Ads))
-			}
+// applyHints adopts the fetch interval and expiry of the playlist now in effect
+func (f *Fetcher) applyHints(ads *models.AdDeliveryResponse) {
+	previous := f.cadence.Interval()
+	f.cadence.SetHint(time.Duration(ads.NextFetchAfter) * time.Second)
+	f.cadence.SetDeadline(ads.ExpiresAt)
+	if current := f.cadence.Interval(); current != previous {
+		log.Printf("[%s] [FETCH] Fetch interval changed by server: %v -> %v", 
+			time.Now().Format("15:04:05.000"), previous, current)
+	}
+}
 
-			// Log ad details and JSON response
-			if len(ads.Ads) > 0 {
-				log.Printf("[%s] [INFO] Received %d ads for display", successTime.Format("15:04:05.000"), len(ads.Ads))
-				
-				// Log full JSON response
-				jsonData, err := json.MarshalIndent(ads, "", "  ")
-				if err != nil {
-					log.Printf("[%s] [WARN] Failed to marshal ads to JSON: %v", successTime.Format("15:04:05.000"), err)
-				} else {
-					log.Printf("[%s] [RESPONSE] Ads JSON Response:\n%s", successTime.Format("15:04:05.000"), string(jsonData))
-				}
-				
-				// Log individual ad summary
-				for i, ad := range ads.Ads {
-					log.Printf("[%s] [INFO] Ad %d: ID=%s, Type=%s, URL=%s", 
-						successTime.Format("15:04:05.000"), i+1, ad.ID, ad.Type, ad.ContentURL)
-				}
-			} else {
-				log.Printf("[%s] [INFO] No ads available for display", successTime.Format("15:04:05.000"))
-				
-				// Log JSON response even when no ads
-				jsonData, err := json.MarshalIndent(ads, "", "  ")
-				if err != nil {
-					log.Printf("[%s] [WARN] Failed to marshal ads to JSON: %v", successTime.Format("15:04:05.000"), err)
-				} else {
-					log.Printf("[%s] [RESPONSE] Ads JSON Response:\n%s", successTime.Format("15:04:05.000"), string(jsonData))
-				}
-			}
+// verifyPlaylist checks a fetched playlist against the pinned key
+// A verified playlist is returned as decoded from the signed payload; it must be issued for this
+// screen and be no older than the current playlist, which survives restarts in storage
+func (f *Fetcher) verifyPlaylist(ads *models.AdDeliveryResponse) (*models.AdDeliveryResponse, error) {
+	f.mu.RLock()
+	key := f.playlistKey
+	f.mu.RUnlock()
 
-			return
+	if key == nil {
+		if ads.Signature != nil {
+			log.Printf("[%s] [WARN] Playlist is signed but no playlist key is configured, signature not checked", 
+				time.Now().Format("15:04:05.000"))
 		}
+		return ads, nil
+	}
 
-		lastErr = err
-		errorTime := time.Now()
-		log.Printf("[%s] [WARN] Ad fetch attempt %d/%d failed: %v", 
-			errorTime.Format("15:04:05.000"), attempt+1, f.retryAttempts+1, err)
+	verified, err := openSignedPlaylist(key, ads.Signature)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.RLock()
+	current := f.lastAds
+	f.mu.RUnlock()
+	if current == nil {
+		current, _ = f.storage.LoadAds()
 	}
+	if err := checkFreshness(verified, f.screenID, current); err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] [OK] Playlist signature verified", time.Now().Format("15:04:05.000"))
+	return verified, nil
+}
 
-	// All retries failed
-	failureTime := time.Now()
-	totalDuration := failureTime.Sub(startTime)
+// notify calls the update callback and every subscriber
+func (f *Fetcher) notify(ads *models.AdDeliveryResponse, diff *PlaylistDiff) {
+	f.mu.RLock()
+	callback := f.onAdsUpdated
+	subscribers := make([]func(*models.AdDeliveryResponse, *PlaylistDiff), len(f.subscribers))
+	copy(subscribers, f.subscribers)
+	f.mu.RUnlock()
+
+	if callback != nil {
+		callback(ads)
+	}
+	for _, subscriber := range subscribers {
+		subscriber(ads, diff)
+	}
+}
+
+// handleNotModified records a fetch cycle where the server reported the playlist unchanged
+// The stored playlist stays as-is and the player is not notified, so rotation isn't reset
+func (f *Fetcher) handleNotModified(startTime time.Time) {
+	now := time.Now()
 
 	f.mu.Lock()
-	f.lastError = lastErr
+	if f.lastAds == nil {
+		if stored, err := f.storage.LoadAds(); err == nil {
+			f.lastAds = stored
+		}
+	}
+	f.lastFetch = now
+	f.lastError = nil
+	f.authFailed = false
+	f.notModifiedCount++
+	current := f.lastAds
 	f.mu.Unlock()
 
-	log.Printf("[%s] [ERROR] Ad fetch cycle failed after %d attempts | Total duration: %v | Error: %v", 
-		failureTime.Format("15:04:05.000"), f.retryAttempts+1, totalDuration, lastErr)
+	f.saveConfirmed(now)
+
+	// The playlist's hints still apply; after a restart they come from the stored copy
+	if current != nil {
+		f.applyHints(current)
+	}
+
+	log.Printf("[%s] [OK] Ads unchanged since last fetch | Total duration: %v", 
+		now.Format("15:04:05.000"), now.Sub(startTime))
 }
 
 // GetLastAds returns the last successfully fetched ads
@@ -218,7 +537,8 @@ func (f *Fetcher) GetStorage() *Storage {
 	return f.storage
 }
 
-// GetLastFetch returns the time of the last successful fetch
+// GetLastFetch returns when the server last confirmed the playlist, by a fetch or over the push channel,
+// including confirmations from before a restart
 func (f *Fetcher) GetLastFetch() time.Time {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
@@ -240,16 +560,53 @@ func (f *Fetcher) GetStats() map[string]interface{} {
 	stats := map[string]interface{}{
 		"lastFetch":  f.lastFetch,
 		"interval":   f.interval.String(),
+		"effectiveInterval": f.cadence.Interval().String(),
 		"adsCount":   0,
+		"notModifiedCount": f.notModifiedCount,
+		"pushEnabled":   f.pushEnabled,
+		"pushConnected": f.pushConnected,
+		"authFailed":    f.authFailed,
+		"signatureRequired": f.playlistKey != nil,
+		"playlistsRejected": f.playlistsRejected,
+		"adsRejected":       len(f.rejectedAds),
+		"adsRejectedTotal":  f.rejectedAdsTotal,
+	}
+
+	if len(f.rejectedAds) > 0 {
+		rejected := make([]models.AdRejection, len(f.rejectedAds))
+		copy(rejected, f.rejectedAds)
+		stats["rejectedAds"] = rejected
 	}
 
 	if f.lastAds != nil {
 		stats["adsCount"] = len(f.lastAds.Ads)
+		if !f.lastAds.ExpiresAt.IsZero() {
+			stats["playlistExpiresAt"] = f.lastAds.ExpiresAt
+			stats["playlistExpired"] = time.Now().After(f.lastAds.ExpiresAt)
+		}
+	}
+	if pin := f.storage.LoadPin(); pin != nil {
+		stats["pinnedSince"] = pin.PinnedAt
+	}
+	if next := f.cadence.NextRun(); !next.IsZero() {
+		stats["nextFetch"] = next
+	}
+
+	transfer := f.client.AdTransferStats()
+	stats["bytesReceived"] = transfer.WireBytes
+	stats["bytesDecoded"] = transfer.DecodedBytes
+	if transfer.Responses > 0 {
+		stats["lastResponseBytes"] = transfer.LastWireBytes
+		stats["lastResponseDecodedBytes"] = transfer.LastDecodedBytes
+		stats["lastResponseEncoding"] = transfer.LastEncoding
 	}
 
 	if f.lastError != nil {
 		stats["lastError"] = f.lastError.Error()
 	}
+	if f.lastRejection != nil {
+		stats["lastRejection"] = f.lastRejection.Error()
+	}
 
 	if !f.lastFetch.IsZero() {
 		stats["timeSinceLastFetch"] = time.Since(f.lastFetch).String()
@@ -265,3 +622,16 @@ func (f *Fetcher) SetOnAdsUpdated(callback func(*models.AdDeliveryResponse)) {
 	f.onAdsUpdated = callback
 }
 
+// Subscribe registers a function called with the new playlist and its diff whenever ads change
+func (f *Fetcher) Subscribe(subscriber func(*models.AdDeliveryResponse, *PlaylistDiff)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribers = append(f.subscribers, subscriber)
+}
+
+// SetOnCommand sets a callback for remote commands received over the push channel
+func (f *Fetcher) SetOnCommand(callback func(*client.CommandPayload)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onCommand = callback
+}
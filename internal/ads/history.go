@@ -0,0 +1,307 @@
+package ads
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mnemoCast-client/internal/db"
+	"mnemoCast-client/internal/models"
+	"mnemoCast-client/pkg/storage"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultHistoryLimit is the number of playlist snapshots kept when not configured
+const DefaultHistoryLimit = 10
+
+// HistoryEntry describes a stored playlist snapshot
+// Index 0 is the newest snapshot (normally the current playlist), 1 the one before it, and so on
+type HistoryEntry struct {
+	Index      int       `json:"index"`
+	PlaylistID string    `json:"playlistId,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	SavedAt    time.Time `json:"savedAt"`
+	AdsCount   int       `json:"adsCount"`
+	key        string
+}
+
+// PlaylistPin holds a rolled-back playlist in place of the server's
+// While it is set the fetcher keeps confirming with the server but doesn't replace the playlist
+type PlaylistPin struct {
+	PlaylistID string    `json:"playlistId,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	PinnedAt   time.Time `json:"pinnedAt"`
+}
+
+// SetHistoryLimit sets how many playlist snapshots are kept; values < 1 disable history
+// The config loader turns an unset playlistHistorySize into DefaultHistoryLimit, so configs use -1 to disable it
+func (s *Storage) SetHistoryLimit(limit int) {
+	if limit < 0 {
+		limit = 0
+	}
+	s.historyLimit = limit
+}
+
+// recordHistory stores a snapshot of a newly saved playlist and prunes old ones
+// Saving the same playlist twice in a row doesn't add a snapshot, and an older snapshot of the
+// same playlist (same playlistId and updatedAt) is moved to the front rather than kept twice
+func (s *Storage) recordHistory(tx storage.Tx, ads *storedAds, data []byte) error {
+	if s.historyLimit < 1 {
+		return nil
+	}
+
+	entries, err := listHistory(tx)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) > 0 {
+		if sameIdentity(&entries[0], ads) {
+			return nil
+		}
+		if latest, err := readSnapshot(tx, entries[0].key); err == nil && samePlaylist(latest, ads) {
+			return nil
+		}
+	}
+
+	// Nanosecond keys sort chronologically and don't collide between quick saves
+	key := storage.SequenceKey(uint64(ads.FetchedAt.UnixNano()))
+	if err := tx.Put(db.BucketPlaylistHistory, key, data); err != nil {
+		return fmt.Errorf("failed to write playlist snapshot: %w", err)
+	}
+
+	// The new snapshot is now at the front, so everything from historyLimit-1 on is surplus
+	kept := 0
+	for _, entry := range entries {
+		if kept < s.historyLimit-1 && !sameIdentity(&entry, ads) {
+			kept++
+			continue
+		}
+		if err := tx.Delete(db.BucketPlaylistHistory, entry.key); err != nil {
+			return fmt.Errorf("failed to prune playlist snapshot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListHistory returns the stored playlist snapshots, newest first
+// Unreadable snapshots are skipped
+func (s *Storage) ListHistory() ([]HistoryEntry, error) {
+	if s.storeErr != nil {
+		return nil, s.storeErr
+	}
+
+	var entries []HistoryEntry
+	err := s.store.View(func(tx storage.Tx) error {
+		var err error
+		entries, err = listHistory(tx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read playlist history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// LoadSnapshot loads the playlist snapshot at index (see ListHistory)
+func (s *Storage) LoadSnapshot(index int) (*models.AdDeliveryResponse, error) {
+	if s.storeErr != nil {
+		return nil, s.storeErr
+	}
+
+	var snapshot *storedAds
+	err := s.store.View(func(tx storage.Tx) error {
+		entries, err := listHistory(tx)
+		if err != nil {
+			return err
+		}
+		if index < 0 || index >= len(entries) {
+			return fmt.Errorf("no playlist snapshot at index %d (%d stored)", index, len(entries))
+		}
+
+		snapshot, err = readSnapshot(tx, entries[index].key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openStored(snapshot)
+}
+
+// Rollback makes the snapshot at index the current playlist and pins it
+// The restored playlist is saved like a fetched one, so it becomes the newest snapshot and the
+// rollback itself can be undone. The pin keeps the fetcher from replacing it with the server's
+// playlist until Unpin is called.
+func (s *Storage) Rollback(index int) (*models.AdDeliveryResponse, error) {
+	adResponse, err := s.LoadSnapshot(index)
+	if err != nil {
+		return nil, err
+	}
+
+	pin := &PlaylistPin{
+		PlaylistID: adResponse.PlaylistID,
+		UpdatedAt:  adResponse.UpdatedAt,
+		PinnedAt:   time.Now(),
+	}
+	if err := s.saveAds(adResponse, pin); err != nil {
+		return nil, fmt.Errorf("failed to restore playlist: %w", err)
+	}
+
+	return adResponse, nil
+}
+
+// LoadPin returns the pin set by Rollback, or nil if the playlist isn't pinned
+func (s *Storage) LoadPin() *PlaylistPin {
+	if s.storeErr != nil {
+		return nil
+	}
+
+	var pin *PlaylistPin
+	s.store.View(func(tx storage.Tx) error {
+		data, err := tx.Get(db.BucketPlaylists, currentPinKey)
+		if err != nil {
+			return err
+		}
+		var parsed PlaylistPin
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return err
+		}
+		pin = &parsed
+		return nil
+	})
+
+	return pin
+}
+
+// Unpin lets the next successful fetch replace a rolled-back playlist again
+func (s *Storage) Unpin() error {
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+
+	err := s.store.Update(func(tx storage.Tx) error {
+		return tx.Delete(db.BucketPlaylists, currentPinKey)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unpin playlist: %w", err)
+	}
+
+	return nil
+}
+
+// putPin stores a playlist pin
+func putPin(tx storage.Tx, pin *PlaylistPin) error {
+	data, err := json.Marshal(pin)
+	if err != nil {
+		return fmt.Errorf("failed to marshal playlist pin: %w", err)
+	}
+	return tx.Put(db.BucketPlaylists, currentPinKey, data)
+}
+
+// importHistory imports snapshot files written by older versions into the database
+func (s *Storage) importHistory() error {
+	files, err := os.ReadDir(s.historyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		key := strings.TrimSuffix(name, ".json")
+		_, err := storage.ImportFile(s.store, filepath.Join(s.historyDir, name), func(tx storage.Tx, data []byte) error {
+			var snapshot storedAds
+			if err := json.Unmarshal(data, &snapshot); err != nil {
+				return fmt.Errorf("failed to parse playlist snapshot: %w", err)
+			}
+			return tx.Put(db.BucketPlaylistHistory, key, data)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listHistory returns the snapshots in the history bucket, newest first
+func listHistory(tx storage.Tx) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	err := tx.ForEach(db.BucketPlaylistHistory, func(key string, data []byte) error {
+		var snapshot storedAds
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil
+		}
+		entries = append(entries, HistoryEntry{
+			PlaylistID: snapshot.PlaylistID,
+			UpdatedAt:  snapshot.UpdatedAt,
+			SavedAt:    snapshot.FetchedAt,
+			AdsCount:   len(snapshot.Ads),
+			key:        key,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys ascend with save time; present newest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	for i := range entries {
+		entries[i].Index = i
+	}
+
+	return entries, nil
+}
+
+// readSnapshot parses the snapshot stored under key
+func readSnapshot(tx storage.Tx, key string) (*storedAds, error) {
+	data, err := tx.Get(db.BucketPlaylistHistory, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("playlist snapshot not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot storedAds
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+// samePlaylist reports whether two stored playlists have the same identity and ads
+func samePlaylist(a, b *storedAds) bool {
+	if a.PlaylistID != b.PlaylistID || !a.UpdatedAt.Equal(b.UpdatedAt) {
+		return false
+	}
+
+	adsA, errA := json.Marshal(a.Ads)
+	adsB, errB := json.Marshal(b.Ads)
+	return errA == nil && errB == nil && bytes.Equal(adsA, adsB)
+}
+
+// sameIdentity reports whether a snapshot has the playlistId and updatedAt of a stored playlist
+// Playlists without either (e.g. written by test-ads) have no identity and never match
+func sameIdentity(entry *HistoryEntry, ads *storedAds) bool {
+	if ads.PlaylistID == "" && ads.UpdatedAt.IsZero() {
+		return false
+	}
+	return entry.PlaylistID == ads.PlaylistID && entry.UpdatedAt.Equal(ads.UpdatedAt)
+}
@@ -0,0 +1,72 @@
+package ads
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mnemoCast-client/internal/client"
+	"mnemoCast-client/internal/models"
+)
+
+func testPlaylist(id string, updatedAt time.Time) *models.AdDeliveryResponse {
+	return &models.AdDeliveryResponse{
+		PlaylistID: id,
+		UpdatedAt:  updatedAt,
+		Ads:        []models.Ad{{ID: id + "-ad", Type: "image", ContentURL: "https://cdn.example.com/" + id + ".jpg"}},
+	}
+}
+
+func TestRollbackPinsPlaylistUntilUnpinned(t *testing.T) {
+	updatedAt := time.Date(2025, 12, 19, 1, 24, 10, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"playlistId":"bad","updatedAt":%q,"ads":[{"id":"bad-ad","type":"image","contentUrl":"https://cdn.example.com/bad.jpg"}]}`,
+			updatedAt.Add(2*time.Hour).Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(client.NewClient(server.URL, "screen-1", "passkey"), "screen-1", t.TempDir(), 60)
+	storage := fetcher.GetStorage()
+
+	// Saving a playlist that is already in the history replaces its older snapshot
+	for _, playlist := range []*models.AdDeliveryResponse{
+		testPlaylist("good", updatedAt),
+		testPlaylist("bad", updatedAt.Add(time.Hour)),
+		testPlaylist("good", updatedAt),
+		testPlaylist("bad", updatedAt.Add(time.Hour)),
+	} {
+		if err := storage.SaveAds(playlist); err != nil {
+			t.Fatalf("SaveAds: %v", err)
+		}
+	}
+	entries, err := storage.ListHistory()
+	if err != nil || len(entries) != 2 || entries[0].PlaylistID != "bad" || entries[1].PlaylistID != "good" {
+		t.Fatalf("history = %+v (%v), want bad then good without duplicates", entries, err)
+	}
+
+	if _, err := storage.Rollback(1); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if storage.LoadPin() == nil {
+		t.Fatal("rollback should pin the restored playlist")
+	}
+
+	fetcher.fetchAds(false)
+	if current, err := storage.LoadAds(); err != nil || current.PlaylistID != "good" {
+		t.Fatalf("current playlist = %+v (%v), want the pinned playlist kept", current, err)
+	}
+	if fetcher.GetLastFetch().IsZero() || fetcher.GetLastError() != nil {
+		t.Errorf("a fetch while pinned should still confirm the playlist (last error: %v)", fetcher.GetLastError())
+	}
+
+	if err := storage.Unpin(); err != nil {
+		t.Fatalf("Unpin: %v", err)
+	}
+	fetcher.fetchAds(false)
+	if current, err := storage.LoadAds(); err != nil || current.PlaylistID != "bad" {
+		t.Errorf("current playlist = %+v (%v), want the server's playlist after unpinning", current, err)
+	}
+}
@@ -0,0 +1,170 @@
+package ads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mnemoCast-client/internal/db"
+	"mnemoCast-client/pkg/storage"
+	"path/filepath"
+	"time"
+)
+
+// Directories inside the media directory
+const (
+	objectDirName = "sha256" // Verified media files named by their SHA-256
+	tempDirName   = "tmp"    // Downloads in progress
+)
+
+// MediaEntry records which stored object holds the content of a URL
+type MediaEntry struct {
+	URL        string    `json:"url"`
+	SHA256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	File       string    `json:"file"` // File name in the object directory
+	FetchedAt  time.Time `json:"fetchedAt"`
+	LastPlayed time.Time `json:"lastPlayed,omitempty"`
+}
+
+// LastUsed returns when the entry's content was last played, or fetched if it never was
+func (e *MediaEntry) LastUsed() time.Time {
+	if e.LastPlayed.After(e.FetchedAt) {
+		return e.LastPlayed
+	}
+	return e.FetchedAt
+}
+
+// GetObjectDir returns the directory of content-addressed media files
+func (s *Storage) GetObjectDir() string {
+	return filepath.Join(s.mediaDir, objectDirName)
+}
+
+// GetTempDir returns the directory for downloads in progress
+func (s *Storage) GetTempDir() string {
+	return filepath.Join(s.mediaDir, tempDirName)
+}
+
+// GetMediaEntry returns the media index entry for a content URL
+// Returns storage.ErrNotFound if the URL was never downloaded
+func (s *Storage) GetMediaEntry(url string) (*MediaEntry, error) {
+	if s.storeErr != nil {
+		return nil, s.storeErr
+	}
+
+	var entry MediaEntry
+	err := s.store.View(func(tx storage.Tx) error {
+		data, err := tx.Get(db.BucketMediaIndex, url)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read media index: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// PutMediaEntry records the object holding a URL's content
+func (s *Storage) PutMediaEntry(entry *MediaEntry) error {
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal media entry: %w", err)
+	}
+
+	err = s.store.Update(func(tx storage.Tx) error {
+		return tx.Put(db.BucketMediaIndex, entry.URL, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update media index: %w", err)
+	}
+	return nil
+}
+
+// TouchMediaEntry records that a URL's content was played at the given time
+// URLs that aren't in the index (e.g. file:// ads) are ignored
+func (s *Storage) TouchMediaEntry(url string, playedAt time.Time) error {
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+
+	err := s.store.Update(func(tx storage.Tx) error {
+		data, err := tx.Get(db.BucketMediaIndex, url)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var entry MediaEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		entry.LastPlayed = playedAt
+
+		data, err = json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return tx.Put(db.BucketMediaIndex, url, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update media index: %w", err)
+	}
+	return nil
+}
+
+// ListMediaEntries returns every media index entry, skipping unreadable ones
+func (s *Storage) ListMediaEntries() ([]MediaEntry, error) {
+	if s.storeErr != nil {
+		return nil, s.storeErr
+	}
+
+	var entries []MediaEntry
+	err := s.store.View(func(tx storage.Tx) error {
+		return tx.ForEach(db.BucketMediaIndex, func(key string, data []byte) error {
+			var entry MediaEntry
+			if err := json.Unmarshal(data, &entry); err == nil {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media index: %w", err)
+	}
+
+	return entries, nil
+}
+
+// DeleteMediaEntries removes the index entries of the given URLs
+func (s *Storage) DeleteMediaEntries(urls []string) error {
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	err := s.store.Update(func(tx storage.Tx) error {
+		for _, url := range urls {
+			if err := tx.Delete(db.BucketMediaIndex, url); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update media index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package ads
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mnemoCast-client/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Merger combines the playlists of several ad sources into the one the player runs
+// The main ad server's playlist is pushed in with UpdatePrimary; every other source is
+// fetched on its own interval. Each ad is attributed to its source, and the merged playlist
+// lists the sources' weights and priorities so the player can share airtime between them.
+type Merger struct {
+	interval time.Duration // Default fetch interval for sources without one
+	sources  []*mergedSource
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.RWMutex
+	publishMu sync.Mutex // Keeps merged playlists reaching the callback in order
+	published *models.AdDeliveryResponse
+
+	// Callback for merged playlist updates
+	onAdsUpdated func(*models.AdDeliveryResponse)
+}
+
+// mergedSource is one source and its last good playlist
+type mergedSource struct {
+	config   models.AdSourceConfig
+	source   Source // nil for the main ad server, which is fed by UpdatePrimary
+	interval time.Duration
+
+	ads       *models.AdDeliveryResponse // Validated and attributed; kept when a fetch fails
+	lastFetch time.Time
+	lastError error
+	rejected  int    // Ads dropped by validation from the last playlist
+	rejection string // Rejections last logged, so a source fetched again doesn't repeat them
+}
+
+// NewMerger creates a merger; sources without an interval are fetched every intervalSeconds
+func NewMerger(intervalSeconds int) *Merger {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Merger{
+		interval: time.Duration(intervalSeconds) * time.Second,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// AddSource adds a source; must be called before Start
+// An adserver entry takes no source and only sets the main ad server's name, weight and priority
+func (m *Merger) AddSource(config models.AdSourceConfig, source Source) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if config.Name == "" {
+		config.Name = config.Type
+	}
+	if config.Weight <= 0 {
+		config.Weight = 1
+	}
+	for _, existing := range m.sources {
+		if existing.config.Name == config.Name {
+			return fmt.Errorf("ad source %s is configured twice", config.Name)
+		}
+	}
+	if (config.Type == models.AdSourceAdServer) != (source == nil) {
+		return fmt.Errorf("ad source %s: only the adserver source is fed without a fetcher", config.Name)
+	}
+
+	interval := time.Duration(config.Interval) * time.Second
+	if interval <= 0 {
+		interval = m.interval
+	}
+	m.sources = append(m.sources, &mergedSource{config: config, source: source, interval: interval})
+	return nil
+}
+
+// SetOnAdsUpdated sets a callback called with each new merged playlist
+func (m *Merger) SetOnAdsUpdated(callback func(*models.AdDeliveryResponse)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onAdsUpdated = callback
+}
+
+// UpdatePrimary replaces the main ad server's playlist and publishes the merged result
+func (m *Merger) UpdatePrimary(adResponse *models.AdDeliveryResponse) {
+	m.mu.Lock()
+	primary := m.primaryLocked()
+	primary.ads = m.attribute(primary, adResponse)
+	primary.lastFetch = time.Now()
+	primary.lastError = nil
+	m.mu.Unlock()
+
+	m.publish()
+}
+
+// primaryLocked returns the main ad server's entry, adding it with defaults if it wasn't configured
+// Callers must hold m.mu for writing
+func (m *Merger) primaryLocked() *mergedSource {
+	for _, source := range m.sources {
+		if source.source == nil {
+			return source
+		}
+	}
+	primary := &mergedSource{config: models.AdSourceConfig{
+		Name:   models.AdSourceAdServer,
+		Type:   models.AdSourceAdServer,
+		Weight: 1,
+	}}
+	m.sources = append([]*mergedSource{primary}, m.sources...)
+	return primary
+}
+
+// Start starts fetching every source in the background
+func (m *Merger) Start() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, source := range m.sources {
+		if source.source == nil {
+			continue
+		}
+		m.wg.Add(1)
+		go m.run(source)
+		log.Printf("[%s] [SOURCE] Source %s (%s) started (interval: %v, weight: %d, priority: %d)",
+			time.Now().Format("15:04:05.000"), source.config.Name, source.config.Type, source.interval, source.config.Weight, source.config.Priority)
+	}
+}
+
+// Stop stops fetching the sources
+func (m *Merger) Stop() {
+	m.cancel()
+	m.wg.Wait()
+	log.Printf("[%s] [SOURCE] Ad sources stopped", time.Now().Format("15:04:05.000"))
+}
+
+// run fetches one source immediately and then on its interval
+func (m *Merger) run(source *mergedSource) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(source.interval)
+	defer ticker.Stop()
+
+	for {
+		m.fetch(source)
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetch fetches one source; on failure its previous playlist stays in the merge
+func (m *Merger) fetch(source *mergedSource) {
+	timestamp := time.Now().Format("15:04:05.000")
+	adResponse, err := source.source.Fetch(m.ctx)
+	if err != nil {
+		if m.ctx.Err() != nil {
+			return
+		}
+		log.Printf("[%s] [SOURCE] [WARN] Failed to fetch ad source %s, keeping its last playlist: %v",
+			timestamp, source.config.Name, err)
+		m.mu.Lock()
+		source.lastError = err
+		m.mu.Unlock()
+		return
+	}
+
+	m.mu.Lock()
+	attributed := m.attribute(source, adResponse)
+	source.lastFetch = time.Now()
+	if len(attributed.Ads) == 0 && source.rejected > 0 && source.ads != nil {
+		// Playing nothing from the source would be worse than its last good playlist
+		source.lastError = fmt.Errorf("all %d ads failed validation", source.rejected)
+		m.mu.Unlock()
+		log.Printf("[%s] [SOURCE] [WARN] Every ad from source %s was rejected, keeping its last playlist",
+			timestamp, source.config.Name)
+		return
+	}
+	source.ads = attributed
+	source.lastError = nil
+	m.mu.Unlock()
+
+	m.publish()
+}
+
+// attribute returns a validated copy of a source's playlist with every ad tagged with the source
+// Rejected ads are counted on the source and logged when they differ from the last fetch.
+// Callers must hold m.mu for writing.
+func (m *Merger) attribute(source *mergedSource, adResponse *models.AdDeliveryResponse) *models.AdDeliveryResponse {
+	attributed := *adResponse
+	attributed.Ads = append([]models.Ad(nil), adResponse.Ads...)
+
+	result := ValidatePlaylist(&attributed)
+	key := ""
+	for _, rejection := range result.Rejected {
+		key += "\x00" + rejection.AdID + "\x00" + rejection.Reason
+	}
+	if key != source.rejection {
+		for _, rejection := range result.Rejected {
+			log.Printf("[%s] [SOURCE] [WARN] Source %s: rejected ad %q (#%d): %s", time.Now().Format("15:04:05.000"),
+				source.config.Name, rejection.AdID, rejection.Index+1, rejection.Reason)
+		}
+		source.rejection = key
+	}
+	source.rejected = len(result.Rejected)
+
+	for i := range attributed.Ads {
+		attributed.Ads[i].Source = source.config.Name
+	}
+	return &attributed
+}
+
+// publish merges the sources and calls the update callback if the result changed
+func (m *Merger) publish() {
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	m.mu.Lock()
+	merged := m.mergeLocked()
+	if m.published != nil && merged.PlaylistID == m.published.PlaylistID &&
+		DiffPlaylists(m.published, merged).IsEmpty() && sameSources(m.published.Sources, merged.Sources) {
+		m.mu.Unlock()
+		return
+	}
+	m.published = merged
+	callback := m.onAdsUpdated
+	m.mu.Unlock()
+
+	log.Printf("[%s] [SOURCE] Merged playlist: %d ads from %d sources",
+		time.Now().Format("15:04:05.000"), len(merged.Ads), len(merged.Sources))
+	if callback != nil {
+		callback(merged)
+	}
+}
+
+// mergeLocked builds the merged playlist
+// An ad ID delivered by several sources is kept from the one with the highest priority, then
+// weight, then the one configured first. Callers must hold m.mu.
+func (m *Merger) mergeLocked() *models.AdDeliveryResponse {
+	ordered := make([]*mergedSource, len(m.sources))
+	copy(ordered, m.sources)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].config.Priority != ordered[j].config.Priority {
+			return ordered[i].config.Priority > ordered[j].config.Priority
+		}
+		return ordered[i].config.Weight > ordered[j].config.Weight
+	})
+
+	merged := &models.AdDeliveryResponse{Ads: []models.Ad{}}
+	owners := make(map[string]string)
+	for _, source := range ordered {
+		if source.ads == nil {
+			continue
+		}
+		if source.source == nil {
+			merged.PlaylistID = source.ads.PlaylistID
+		}
+		if source.ads.UpdatedAt.After(merged.UpdatedAt) {
+			merged.UpdatedAt = source.ads.UpdatedAt
+		}
+		for _, ad := range source.ads.Ads {
+			if owner, ok := owners[ad.ID]; ok {
+				log.Printf("[%s] [SOURCE] [WARN] Ad %q from source %s ignored, already delivered by %s",
+					time.Now().Format("15:04:05.000"), ad.ID, source.config.Name, owner)
+				continue
+			}
+			owners[ad.ID] = source.config.Name
+			merged.Ads = append(merged.Ads, ad)
+		}
+	}
+
+	for _, source := range m.sources {
+		merged.Sources = append(merged.Sources, models.SourceWeight{
+			Name:     source.config.Name,
+			Weight:   source.config.Weight,
+			Priority: source.config.Priority,
+		})
+	}
+	return merged
+}
+
+// sameSources reports whether two source lists are identical
+func sameSources(a, b []models.SourceWeight) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetPlaylist returns the last merged playlist, or nil before the first merge
+func (m *Merger) GetPlaylist() *models.AdDeliveryResponse {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.published
+}
+
+// GetStats returns per-source statistics
+func (m *Merger) GetStats() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sources := make([]map[string]interface{}, 0, len(m.sources))
+	for _, source := range m.sources {
+		stats := map[string]interface{}{
+			"name":     source.config.Name,
+			"type":     source.config.Type,
+			"weight":   source.config.Weight,
+			"priority": source.config.Priority,
+			"adsCount": 0,
+			"rejected": source.rejected,
+		}
+		if source.source != nil {
+			stats["interval"] = source.interval.String()
+		}
+		if source.ads != nil {
+			stats["adsCount"] = len(source.ads.Ads)
+		}
+		if !source.lastFetch.IsZero() {
+			stats["lastFetch"] = source.lastFetch
+		}
+		if source.lastError != nil {
+			stats["lastError"] = source.lastError.Error()
+		}
+		sources = append(sources, stats)
+	}
+
+	stats := map[string]interface{}{
+		"sources":  sources,
+		"adsCount": 0,
+	}
+	if m.published != nil {
+		stats["adsCount"] = len(m.published.Ads)
+	}
+	return stats
+}
@@ -0,0 +1,119 @@
+package ads
+
+import (
+	"log"
+	"mnemoCast-client/internal/client"
+	"mnemoCast-client/internal/retry"
+	"time"
+)
+
+// runPush keeps the server push channel connected, reconnecting with backoff when it drops
+// While disconnected the fetcher falls back to interval polling
+func (f *Fetcher) runPush() {
+	defer f.wg.Done()
+
+	failures := 0
+	for {
+		err := f.client.StreamEvents(f.ctx, f.screenID, f.lastEventID, f.onPushConnected, f.handleEvent)
+
+		wasConnected := f.setPushConnected(false)
+		if f.ctx.Err() != nil {
+			return
+		}
+
+		if wasConnected {
+			failures = 0
+			log.Printf("[%s] [PUSH] Event stream dropped, falling back to polling: %v",
+				time.Now().Format("15:04:05.000"), err)
+		}
+		failures++
+
+		policy := f.client.RetryPolicy()
+		delay := policy.Backoff(failures)
+		if client.IsAuthError(err) {
+			// Rejected credentials won't recover quickly; don't hammer the server
+			delay = policy.MaxDelay
+		}
+		log.Printf("[%s] [PUSH] Reconnecting event stream in %v (%v)",
+			time.Now().Format("15:04:05.000"), delay, err)
+		if retry.Sleep(f.ctx, delay) != nil {
+			return
+		}
+	}
+}
+
+// onPushConnected marks the push channel as up and catches up on changes missed while it was down
+func (f *Fetcher) onPushConnected() {
+	f.setPushConnected(true)
+	f.confirmPush()
+	f.requestFetch(false)
+}
+
+// setPushConnected updates the push state and returns the previous value
+func (f *Fetcher) setPushConnected(connected bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	previous := f.pushConnected
+	f.pushConnected = connected
+	return previous
+}
+
+// handleEvent dispatches a single server-pushed event
+func (f *Fetcher) handleEvent(event client.ServerEvent) {
+	if event.ID != "" {
+		f.lastEventID = event.ID
+	}
+	f.confirmPush()
+
+	switch event.Type {
+	case client.EventPlaylistChanged:
+		f.requestFetch(false)
+	case client.EventReload:
+		f.requestFetch(true)
+	case client.EventCommand:
+		cmd, err := event.Command()
+		if err != nil {
+			log.Printf("[%s] [PUSH] [WARN] Ignoring malformed command: %v", time.Now().Format("15:04:05.000"), err)
+			return
+		}
+		log.Printf("[%s] [PUSH] Received command: %s", time.Now().Format("15:04:05.000"), cmd.Command)
+
+		f.mu.RLock()
+		callback := f.onCommand
+		f.mu.RUnlock()
+		if callback != nil {
+			callback(cmd)
+		}
+	default:
+		log.Printf("[%s] [PUSH] Ignoring unknown event type: %s", time.Now().Format("15:04:05.000"), event.Type)
+	}
+}
+
+// requestFetch asks the fetch loop to fetch immediately
+// Requests coalesce while one is pending; a pending reload is never downgraded
+func (f *Fetcher) requestFetch(reload bool) {
+	select {
+	case f.fetchRequests <- reload:
+		return
+	default:
+	}
+
+	if reload {
+		// Replace a pending plain fetch with the reload
+		select {
+		case <-f.fetchRequests:
+		default:
+		}
+		select {
+		case f.fetchRequests <- true:
+		default:
+		}
+	}
+}
+
+// IsPushConnected reports whether the server push channel is currently connected
+func (f *Fetcher) IsPushConnected() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.pushConnected
+}
@@ -0,0 +1,121 @@
+package ads
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"mnemoCast-client/internal/client"
+	"mnemoCast-client/internal/retry"
+)
+
+func TestPushReconnectsWithLastEventIDAndDispatchesCommands(t *testing.T) {
+	var mu sync.Mutex
+	var resumedFrom []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/screens/screen-1/ads/deliver":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"ads":[]}`)
+		case "/api/v1/screens/screen-1/events":
+			mu.Lock()
+			resumedFrom = append(resumedFrom, r.Header.Get("Last-Event-ID"))
+			connection := len(resumedFrom)
+			mu.Unlock()
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			if connection == 1 {
+				// Pushed the moment the stream opens, then the connection drops
+				fmt.Fprint(w, "id: 7\nevent: command\ndata: {\"command\":\"pause\"}\n\n")
+				return
+			}
+			fmt.Fprint(w, "id: 8\nevent: command\ndata: {\"command\":\"resume\"}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	adClient := client.NewClient(server.URL, "screen-1", "passkey")
+	adClient.SetRetryPolicy(retry.NewPolicy(3, 10*time.Millisecond, 20*time.Millisecond))
+	fetcher := NewFetcher(adClient, "screen-1", t.TempDir(), 3600)
+	fetcher.SetPushEnabled(true)
+
+	commands := make(chan string, 4)
+	fetcher.SetOnCommand(func(cmd *client.CommandPayload) {
+		commands <- cmd.Command
+	})
+	fetcher.Start()
+	defer fetcher.Stop()
+
+	for _, want := range []string{"pause", "resume"} {
+		select {
+		case got := <-commands:
+			if got != want {
+				t.Fatalf("command = %s, want %s", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(resumedFrom) < 2 || resumedFrom[0] != "" || resumedFrom[1] != "7" {
+		t.Errorf("Last-Event-ID per connection = %q, want \"\" then \"7\"", resumedFrom)
+	}
+	if !fetcher.IsPushConnected() {
+		t.Error("push channel should be connected")
+	}
+}
+
+func TestPollingResumesWhilePushedOnceThePlaylistExpires(t *testing.T) {
+	var mu sync.Mutex
+	deliveries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/screens/screen-1/ads/deliver":
+			mu.Lock()
+			deliveries++
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"ads":[],"expiresAt":"2000-01-01T00:00:00Z"}`)
+		case "/api/v1/screens/screen-1/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	adClient := client.NewClient(server.URL, "screen-1", "passkey")
+	fetcher := NewFetcher(adClient, "screen-1", t.TempDir(), 1)
+	fetcher.SetPushEnabled(true)
+	fetcher.Start()
+	defer fetcher.Stop()
+
+	// The initial fetch and the catch-up on connect, then at least one poll for the expired playlist
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		count := deliveries
+		mu.Unlock()
+		if count >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d deliveries, want polling to continue for an expired playlist", count)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !fetcher.IsPushConnected() {
+		t.Error("push channel should be connected")
+	}
+}
@@ -0,0 +1,83 @@
+package ads
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"mnemoCast-client/internal/models"
+	"mnemoCast-client/pkg/signing"
+	"time"
+)
+
+// openSignedPlaylist verifies a playlist's signature against the pinned key and returns
+// the playlist decoded from the signed payload, so nothing outside the signature is trusted
+// Every ad must carry a checksum, otherwise its media would not be covered by the signature.
+func openSignedPlaylist(key ed25519.PublicKey, signature *models.PlaylistSignature) (*models.AdDeliveryResponse, error) {
+	if signature == nil {
+		return nil, signing.ErrUnsignedPlaylist
+	}
+	if err := signing.VerifyPlaylist(key, signature.Payload, signature.Signature); err != nil {
+		if signature.KeyID != "" {
+			return nil, fmt.Errorf("%w (key %s)", err, signature.KeyID)
+		}
+		return nil, err
+	}
+
+	var adResponse models.AdDeliveryResponse
+	if err := json.Unmarshal(signature.Payload, &adResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse signed playlist: %w", err)
+	}
+	for i := range adResponse.Ads {
+		ad := &adResponse.Ads[i]
+		if ad.ContentURL != "" && ad.SHA256() == "" {
+			return nil, fmt.Errorf("signed playlist has no checksum for ad %s", ad.ID)
+		}
+	}
+	adResponse.Signature = signature
+	return &adResponse, nil
+}
+
+// checkFreshness rejects a signed playlist issued for another screen or older than the current one
+// A signature only proves the server issued the playlist at some point; without these checks an
+// old or foreign envelope could be replayed to roll the screen back. current may be nil.
+func checkFreshness(adResponse *models.AdDeliveryResponse, screenID string, current *models.AdDeliveryResponse) error {
+	if adResponse.ScreenID != screenID {
+		if adResponse.ScreenID == "" {
+			return fmt.Errorf("signed playlist does not name its screen")
+		}
+		return fmt.Errorf("signed playlist was issued for screen %s", adResponse.ScreenID)
+	}
+	if adResponse.UpdatedAt.IsZero() {
+		return fmt.Errorf("signed playlist has no updatedAt")
+	}
+	if current != nil && adResponse.UpdatedAt.Before(current.UpdatedAt) {
+		return fmt.Errorf("signed playlist from %s is older than the current one from %s",
+			adResponse.UpdatedAt.Format(time.RFC3339), current.UpdatedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ParsePlaylist decodes a playlist file in the ad delivery response format or a signed envelope around one
+// With a pinned key only signed playlists that verify are accepted, the same as for the ad server
+func ParsePlaylist(data []byte, key ed25519.PublicKey) (*models.AdDeliveryResponse, error) {
+	var envelope signing.Envelope
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.IsEnvelope() {
+		payload, sig, err := envelope.Decode()
+		if err != nil {
+			return nil, err
+		}
+		signature := &models.PlaylistSignature{Payload: payload, Signature: sig, KeyID: envelope.KeyID}
+		if key != nil {
+			return openSignedPlaylist(key, signature)
+		}
+		data = payload
+	} else if key != nil {
+		return nil, signing.ErrUnsignedPlaylist
+	}
+
+	var adResponse models.AdDeliveryResponse
+	if err := json.Unmarshal(data, &adResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist: %w", err)
+	}
+	return &adResponse, nil
+}
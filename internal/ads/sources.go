@@ -0,0 +1,182 @@
+package ads
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"mnemoCast-client/internal/client"
+	"mnemoCast-client/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source supplies ads that are merged into the screen's playlist
+// Sources are fetched independently; a failing source keeps its last playlist
+type Source interface {
+	// Fetch returns the source's current playlist
+	Fetch(ctx context.Context) (*models.AdDeliveryResponse, error)
+}
+
+// NewSource creates the source described by config
+// Relative paths are resolved against adsDir; server sources fetch screenID's ads with serverClient.
+// With a pinned playlist key, file and server playlists must be signed with it and directory
+// sources are refused, since nothing vouches for files dropped into a directory.
+func NewSource(config models.AdSourceConfig, adsDir string, serverClient *client.Client, screenID string, key ed25519.PublicKey) (Source, error) {
+	path := config.Path
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(adsDir, path)
+	}
+
+	switch config.Type {
+	case models.AdSourceDirectory:
+		if path == "" {
+			return nil, fmt.Errorf("ad source %s: directory sources need a path", config.Name)
+		}
+		if key != nil {
+			return nil, fmt.Errorf("ad source %s: directory sources can't be signed and are disabled while playlistPublicKey is set", config.Name)
+		}
+		return NewDirectorySource(config.Name, path), nil
+	case models.AdSourceFile:
+		if path == "" {
+			return nil, fmt.Errorf("ad source %s: file sources need a path", config.Name)
+		}
+		return NewFileSource(path, key), nil
+	case models.AdSourceServer:
+		if config.URL == "" || serverClient == nil {
+			return nil, fmt.Errorf("ad source %s: server sources need a url", config.Name)
+		}
+		return NewServerSource(serverClient, screenID, key), nil
+	default:
+		return nil, fmt.Errorf("ad source %s: unknown type %q", config.Name, config.Type)
+	}
+}
+
+// serverSource fetches a playlist from a secondary ad server
+type serverSource struct {
+	client   *client.Client
+	screenID string
+	key      ed25519.PublicKey // Playlists must be signed with this key when set
+
+	mu         sync.Mutex
+	validators *models.CacheValidators
+	last       *models.AdDeliveryResponse
+}
+
+// NewServerSource creates a source for an ad server speaking the ad delivery API
+// A non-nil key requires every playlist to be signed with it
+func NewServerSource(adClient *client.Client, screenID string, key ed25519.PublicKey) Source {
+	return &serverSource{client: adClient, screenID: screenID, key: key}
+}
+
+// Fetch fetches the playlist, reusing the previous one when the server reports it unchanged
+func (s *serverSource) Fetch(ctx context.Context) (*models.AdDeliveryResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	adResponse, validators, err := s.client.GetAdsConditional(ctx, s.screenID, s.validators)
+	if errors.Is(err, client.ErrNotModified) && s.last != nil {
+		return s.last, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.key != nil {
+		if adResponse, err = openSignedPlaylist(s.key, adResponse.Signature); err != nil {
+			return nil, err
+		}
+		if err := checkFreshness(adResponse, s.screenID, s.last); err != nil {
+			return nil, err
+		}
+	}
+
+	s.validators = validators
+	s.last = adResponse
+	return adResponse, nil
+}
+
+// fileSource reads a playlist file in the ad delivery response format
+type fileSource struct {
+	path string
+	key  ed25519.PublicKey // The file must be a signed envelope when set
+}
+
+// NewFileSource creates a source for a static playlist file, either plain or a signed envelope
+// A non-nil key requires the file to be signed with it
+func NewFileSource(path string, key ed25519.PublicKey) Source {
+	return &fileSource{path: path, key: key}
+}
+
+// Fetch reads the playlist file
+func (s *fileSource) Fetch(ctx context.Context) (*models.AdDeliveryResponse, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read playlist file: %w", err)
+	}
+
+	adResponse, err := ParsePlaylist(data, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("playlist file %s: %w", s.path, err)
+	}
+	return adResponse, nil
+}
+
+// directorySource turns the media files in a directory into ads
+type directorySource struct {
+	name string
+	dir  string
+}
+
+// NewDirectorySource creates a source with one ad per media file in dir
+// Ad IDs are "<name>/<file name>"; the type comes from the file extension and other files are ignored
+func NewDirectorySource(name, dir string) Source {
+	return &directorySource{name: name, dir: dir}
+}
+
+// Fetch lists the directory, in file name order
+func (s *directorySource) Fetch(ctx context.Context) (*models.AdDeliveryResponse, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ad directory: %w", err)
+	}
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	adResponse := &models.AdDeliveryResponse{Ads: []models.Ad{}}
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(name))
+		adType, ok := typeAliases[strings.TrimPrefix(ext, ".")]
+		if !ok {
+			continue
+		}
+
+		adResponse.Ads = append(adResponse.Ads, models.Ad{
+			ID:         s.name + "/" + name,
+			Title:      strings.TrimSuffix(name, filepath.Ext(name)),
+			Type:       adType,
+			ContentURL: "file://" + filepath.Join(dir, name),
+			Size:       info.Size(),
+		})
+		if info.ModTime().After(adResponse.UpdatedAt) {
+			adResponse.UpdatedAt = info.ModTime()
+		}
+	}
+
+	sort.Slice(adResponse.Ads, func(i, j int) bool {
+		return adResponse.Ads[i].ID < adResponse.Ads[j].ID
+	})
+	return adResponse, nil
+}
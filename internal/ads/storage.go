@@ -1,36 +1,130 @@
 package ads
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"mnemoCast-client/internal/db"
 	"mnemoCast-client/internal/models"
+	"mnemoCast-client/pkg/storage"
 	"os"
 	"path/filepath"
 	"time"
 )
 
-// Storage handles ad storage in the filesystem
+// Keys in the playlists bucket
+const (
+	currentPlaylistKey   = "current"
+	currentValidatorsKey = "current.validators"
+	currentConfirmedKey  = "current.confirmed"
+	currentPinKey        = "current.pin"
+)
+
+// ErrNoPlaylist is returned by LoadAds when no playlist has been stored yet
+var ErrNoPlaylist = errors.New("no playlist stored")
+
+// Storage handles ad storage
+// Playlists live in the screen database; media files stay on the filesystem under adsDir.
+// current_ads.json and the history directory are still read as an import path.
 type Storage struct {
 	adsDir      string
-	adsFile     string
+	adsFile     string // Legacy/import playlist file
+	validatorsFile string // Legacy/import validators file
 	mediaDir    string
+	historyDir  string // Legacy/import snapshot directory
+	historyLimit int // Playlist snapshots kept for rollback
+	playlistKey ed25519.PublicKey // If set, stored playlists must carry a valid signature
+
+	store    storage.Store
+	storeErr error // Set if the database could not be opened
+}
+
+// storedAds is the stored format of the current playlist and its history snapshots
+type storedAds struct {
+	FetchedAt   time.Time                `json:"fetchedAt"`
+	PlaylistID  string                   `json:"playlistId,omitempty"`
+	UpdatedAt   time.Time                `json:"updatedAt"`
+	Ads         []models.Ad              `json:"ads"`
+	AdsCount    int                      `json:"adsCount"`
+	NextFetchAfter int                   `json:"nextFetchAfter,omitempty"`
+	ExpiresAt   time.Time                `json:"expiresAt,omitempty"`
+	Signature   *models.PlaylistSignature `json:"signature,omitempty"`
+}
+
+// toResponse converts the stored playlist back into a delivery response
+func (a *storedAds) toResponse() *models.AdDeliveryResponse {
+	return &models.AdDeliveryResponse{
+		PlaylistID: a.PlaylistID,
+		UpdatedAt:  a.UpdatedAt,
+		Ads:        a.Ads,
+		NextFetchAfter: a.NextFetchAfter,
+		ExpiresAt:  a.ExpiresAt,
+		Signature:  a.Signature,
+	}
+}
+
+// SetPlaylistKey pins the key stored playlists must be signed with
+// Once set, playlists that are unsigned or fail verification are not loaded,
+// so a playlist written into the database by hand can't be played
+func (s *Storage) SetPlaylistKey(key ed25519.PublicKey) {
+	s.playlistKey = key
+}
+
+// openStored returns a stored playlist, checking its signature if a key is pinned
+// Ads that fail validation are dropped, so hand-written playlists get the same checks as fetched ones;
+// they were logged when the playlist came in, so they are dropped silently here
+func (s *Storage) openStored(stored *storedAds) (*models.AdDeliveryResponse, error) {
+	adResponse := stored.toResponse()
+	if s.playlistKey != nil {
+		var err error
+		adResponse, err = openSignedPlaylist(s.playlistKey, stored.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("stored playlist rejected: %w", err)
+		}
+	}
+
+	ValidatePlaylist(adResponse)
+	return adResponse, nil
 }
 
 // NewStorage creates a new ad storage
+// JSON files left by older versions (or copied in by hand) are imported on creation
 func NewStorage(configDir string) *Storage {
 	adsDir := filepath.Join(configDir, "ads")
-	return &Storage{
+	s := &Storage{
 		adsDir:   adsDir,
 		adsFile:  filepath.Join(adsDir, "current_ads.json"),
+		validatorsFile: filepath.Join(adsDir, "current_ads.validators.json"),
 		mediaDir: filepath.Join(adsDir, "media"),
+		historyDir: filepath.Join(adsDir, "history"),
+		historyLimit: DefaultHistoryLimit,
+	}
+
+	s.store, s.storeErr = db.Open(configDir)
+	if s.storeErr != nil {
+		log.Printf("[ADS] [ERROR] Failed to open ad database: %v", s.storeErr)
+		return s
 	}
+
+	if err := s.importLegacy(); err != nil {
+		log.Printf("[ADS] [WARN] Failed to import ads from JSON files: %v", err)
+	}
+
+	return s
 }
 
-// SaveAds saves the fetched ads to the filesystem
+// SaveAds saves the fetched ads
+// The playlist, the removal of stale validators and the history snapshot commit together
 func (s *Storage) SaveAds(adResponse *models.AdDeliveryResponse) error {
-	// Ensure ads directory exists
-	if err := os.MkdirAll(s.adsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create ads directory: %w", err)
+	return s.saveAds(adResponse, nil)
+}
+
+// saveAds saves a playlist and, if pin is set, pins it in the same transaction
+func (s *Storage) saveAds(adResponse *models.AdDeliveryResponse, pin *PlaylistPin) error {
+	if s.storeErr != nil {
+		return s.storeErr
 	}
 
 	// Ensure media directory exists
@@ -39,63 +133,236 @@ func (s *Storage) SaveAds(adResponse *models.AdDeliveryResponse) error {
 	}
 
 	// Create metadata structure with fetch timestamp
-	adsMetadata := struct {
-		FetchedAt   time.Time                `json:"fetchedAt"`
-		PlaylistID  string                   `json:"playlistId,omitempty"`
-		UpdatedAt   time.Time                `json:"updatedAt"`
-		Ads         []models.Ad              `json:"ads"`
-		AdsCount    int                      `json:"adsCount"`
-	}{
+	adsMetadata := storedAds{
 		FetchedAt:  time.Now(),
 		PlaylistID: adResponse.PlaylistID,
 		UpdatedAt:  adResponse.UpdatedAt,
 		Ads:        adResponse.Ads,
 		AdsCount:   len(adResponse.Ads),
+		NextFetchAfter: adResponse.NextFetchAfter,
+		ExpiresAt:  adResponse.ExpiresAt,
+		Signature:  adResponse.Signature,
 	}
 
-	// Save ads metadata to JSON file
-	data, err := json.MarshalIndent(adsMetadata, "", "  ")
+	data, err := json.Marshal(adsMetadata)
 	if err != nil {
 		return fmt.Errorf("failed to marshal ads: %w", err)
 	}
 
-	// Write with restricted permissions
-	if err := os.WriteFile(s.adsFile, data, 0600); err != nil {
-		return fmt.Errorf("failed to save ads file: %w", err)
+	err = s.store.Update(func(tx storage.Tx) error {
+		if err := tx.Put(db.BucketPlaylists, currentPlaylistKey, data); err != nil {
+			return err
+		}
+
+		// Validators describe the previous playlist, so they no longer apply
+		if err := tx.Delete(db.BucketPlaylists, currentValidatorsKey); err != nil {
+			return err
+		}
+
+		if pin != nil {
+			if err := putPin(tx, pin); err != nil {
+				return err
+			}
+		}
+
+		return s.recordHistory(tx, &adsMetadata, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save ads: %w", err)
 	}
 
 	return nil
 }
 
-// LoadAds loads ads from the filesystem
+// SaveValidators persists the HTTP cache validators for the current playlist
+// Must be called after SaveAds, which discards validators of the previous playlist
+func (s *Storage) SaveValidators(validators *models.CacheValidators) error {
+	if validators.IsEmpty() {
+		return nil
+	}
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+
+	data, err := json.Marshal(validators)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache validators: %w", err)
+	}
+
+	err = s.store.Update(func(tx storage.Tx) error {
+		return tx.Put(db.BucketPlaylists, currentValidatorsKey, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save cache validators: %w", err)
+	}
+
+	return nil
+}
+
+// LoadValidators loads the HTTP cache validators for the current playlist
+// Returns nil if there is no playlist or no validators were stored for it
+func (s *Storage) LoadValidators() *models.CacheValidators {
+	if s.storeErr != nil {
+		return nil
+	}
+
+	var validators *models.CacheValidators
+	s.store.View(func(tx storage.Tx) error {
+		if _, err := tx.Get(db.BucketPlaylists, currentPlaylistKey); err != nil {
+			return err
+		}
+
+		data, err := tx.Get(db.BucketPlaylists, currentValidatorsKey)
+		if err != nil {
+			return err
+		}
+
+		var parsed models.CacheValidators
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return err
+		}
+		validators = &parsed
+		return nil
+	})
+
+	// Validators of a playlist that fails verification would keep the server from resending it
+	if validators != nil && s.playlistKey != nil {
+		if _, err := s.LoadAds(); err != nil {
+			return nil
+		}
+	}
+
+	return validators
+}
+
+// SaveConfirmed records when the server last confirmed the current playlist (a fetch or a 304)
+func (s *Storage) SaveConfirmed(at time.Time) error {
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+
+	data, err := json.Marshal(at)
+	if err != nil {
+		return fmt.Errorf("failed to marshal confirmation time: %w", err)
+	}
+
+	err = s.store.Update(func(tx storage.Tx) error {
+		return tx.Put(db.BucketPlaylists, currentConfirmedKey, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save confirmation time: %w", err)
+	}
+
+	return nil
+}
+
+// LoadConfirmed returns when the server last confirmed the current playlist
+// Falls back to when the playlist was saved, and returns zero if there is no playlist
+func (s *Storage) LoadConfirmed() time.Time {
+	if s.storeErr != nil {
+		return time.Time{}
+	}
+
+	var confirmed time.Time
+	s.store.View(func(tx storage.Tx) error {
+		data, err := tx.Get(db.BucketPlaylists, currentPlaylistKey)
+		if err != nil {
+			return err
+		}
+		var adsMetadata storedAds
+		if err := json.Unmarshal(data, &adsMetadata); err != nil {
+			return err
+		}
+		confirmed = adsMetadata.FetchedAt
+
+		if data, err := tx.Get(db.BucketPlaylists, currentConfirmedKey); err == nil {
+			var at time.Time
+			if json.Unmarshal(data, &at) == nil && at.After(confirmed) {
+				confirmed = at
+			}
+		}
+		return nil
+	})
+
+	return confirmed
+}
+
+// LoadAds loads the current playlist
 func (s *Storage) LoadAds() (*models.AdDeliveryResponse, error) {
-	// Check if ads file exists
-	if _, err := os.Stat(s.adsFile); os.IsNotExist(err) {
-		return nil, fmt.Errorf("ads file not found")
+	if s.storeErr != nil {
+		return nil, s.storeErr
 	}
 
-	data, err := os.ReadFile(s.adsFile)
+	var adsMetadata storedAds
+	err := s.store.View(func(tx storage.Tx) error {
+		data, err := tx.Get(db.BucketPlaylists, currentPlaylistKey)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &adsMetadata)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNoPlaylist
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read ads file: %w", err)
+		return nil, fmt.Errorf("failed to load ads: %w", err)
 	}
 
-	var adsMetadata struct {
-		FetchedAt   time.Time                `json:"fetchedAt"`
-		PlaylistID  string                   `json:"playlistId,omitempty"`
-		UpdatedAt   time.Time                `json:"updatedAt"`
-		Ads         []models.Ad              `json:"ads"`
-		AdsCount    int                      `json:"adsCount"`
+	return s.openStored(&adsMetadata)
+}
+
+// importLegacy imports current_ads.json, its validators and history snapshots into the database
+// Each file is imported once, and again whenever it is replaced
+func (s *Storage) importLegacy() error {
+	var rejected []models.AdRejection
+	imported, err := storage.ImportFile(s.store, s.adsFile, func(tx storage.Tx, data []byte) error {
+		var adsMetadata storedAds
+		if err := json.Unmarshal(data, &adsMetadata); err != nil {
+			return fmt.Errorf("failed to parse ads file: %w", err)
+		}
+		// Validation works on a copy; the file is stored as written and checked again on every load
+		rejected = ValidatePlaylist(adsMetadata.toResponse()).Rejected
+		adsMetadata.AdsCount = len(adsMetadata.Ads)
+		if adsMetadata.FetchedAt.IsZero() {
+			// Hand-written files may omit it; it orders the history snapshot
+			adsMetadata.FetchedAt = time.Now()
+		}
+
+		encoded, err := json.Marshal(adsMetadata)
+		if err != nil {
+			return err
+		}
+		if err := tx.Put(db.BucketPlaylists, currentPlaylistKey, encoded); err != nil {
+			return err
+		}
+		if err := tx.Delete(db.BucketPlaylists, currentValidatorsKey); err != nil {
+			return err
+		}
+		return s.recordHistory(tx, &adsMetadata, encoded)
+	})
+	if err != nil {
+		return err
 	}
+	if imported {
+		log.Printf("[ADS] Imported playlist from %s", s.adsFile)
+		for _, rejection := range rejected {
+			log.Printf("[%s] [ADS] [WARN] Imported ad %q (#%d) will not play: %s", time.Now().Format("15:04:05.000"),
+				rejection.AdID, rejection.Index+1, rejection.Reason)
+		}
 
-	if err := json.Unmarshal(data, &adsMetadata); err != nil {
-		return nil, fmt.Errorf("failed to parse ads file: %w", err)
+		// Validators only apply to the playlist they came with
+		if _, err := storage.ImportFile(s.store, s.validatorsFile, func(tx storage.Tx, data []byte) error {
+			var validators models.CacheValidators
+			if err := json.Unmarshal(data, &validators); err != nil {
+				return err
+			}
+			return tx.Put(db.BucketPlaylists, currentValidatorsKey, data)
+		}); err != nil {
+			return err
+		}
 	}
 
-	return &models.AdDeliveryResponse{
-		PlaylistID: adsMetadata.PlaylistID,
-		UpdatedAt:  adsMetadata.UpdatedAt,
-		Ads:        adsMetadata.Ads,
-	}, nil
+	return s.importHistory()
 }
 
 // GetMediaDir returns the media directory path
@@ -108,9 +375,15 @@ func (s *Storage) GetAdsDir() string {
 	return s.adsDir
 }
 
-// Exists checks if ads file exists
+// Exists checks if a current playlist is stored
 func (s *Storage) Exists() bool {
-	_, err := os.Stat(s.adsFile)
+	if s.storeErr != nil {
+		return false
+	}
+	err := s.store.View(func(tx storage.Tx) error {
+		_, err := tx.Get(db.BucketPlaylists, currentPlaylistKey)
+		return err
+	})
 	return err == nil
 }
 
@@ -124,4 +397,3 @@ func (s *Storage) EnsureAdMediaDir(adID string) error {
 	adMediaDir := filepath.Join(s.mediaDir, adID)
 	return os.MkdirAll(adMediaDir, 0755)
 }
-
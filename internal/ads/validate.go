@@ -0,0 +1,130 @@
+package ads
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mnemoCast-client/internal/models"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Canonical ad types understood by the renderers
+const (
+	TypeImage = "image"
+	TypeVideo = "video"
+	TypeHTML  = "html"
+	TypeText  = "text"
+)
+
+// typeAliases maps type strings and file extensions to canonical ad types
+var typeAliases = map[string]string{
+	"image": TypeImage, "jpg": TypeImage, "jpeg": TypeImage, "png": TypeImage,
+	"gif": TypeImage, "webp": TypeImage, "bmp": TypeImage,
+	"video": TypeVideo, "mp4": TypeVideo, "webm": TypeVideo, "mov": TypeVideo,
+	"avi": TypeVideo, "mkv": TypeVideo, "m4v": TypeVideo,
+	"html": TypeHTML, "htm": TypeHTML,
+	"text": TypeText, "txt": TypeText,
+}
+
+// ValidationResult describes what ValidatePlaylist changed
+type ValidationResult struct {
+	Rejected   []models.AdRejection
+	Normalized int // Ads that were kept but had fields rewritten
+}
+
+// ValidatePlaylist normalizes the ads of a playlist in place and removes the ones that can't be played
+// Types are canonicalized (e.g. "jpg" becomes "image", a missing type is taken from the URL's
+// extension) and checksums lowercased. Ads with no ID, a repeated ID, an unknown type, a missing
+// or unsupported content URL, negative durations or sizes, a bad checksum or an end before their
+// start are rejected with a reason.
+func ValidatePlaylist(adResponse *models.AdDeliveryResponse) *ValidationResult {
+	result := &ValidationResult{}
+	seen := make(map[string]bool)
+	valid := make([]models.Ad, 0, len(adResponse.Ads))
+
+	for i, ad := range adResponse.Ads {
+		normalized, changed, err := normalizeAd(ad)
+		if err == nil && seen[normalized.ID] {
+			err = fmt.Errorf("duplicate id")
+		}
+		if err != nil {
+			result.Rejected = append(result.Rejected, models.AdRejection{
+				AdID:   ad.ID,
+				Index:  i,
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		seen[normalized.ID] = true
+		if changed {
+			result.Normalized++
+		}
+		valid = append(valid, normalized)
+	}
+
+	adResponse.Ads = valid
+	return result
+}
+
+// normalizeAd returns the canonical form of an ad and whether it differs, or why the ad is invalid
+func normalizeAd(ad models.Ad) (models.Ad, bool, error) {
+	original := ad
+	ad.ID = strings.TrimSpace(ad.ID)
+	ad.ContentURL = strings.TrimSpace(ad.ContentURL)
+
+	if ad.ID == "" {
+		return ad, false, fmt.Errorf("missing id")
+	}
+	if ad.ContentURL == "" {
+		return ad, false, fmt.Errorf("missing contentUrl")
+	}
+	parsed, err := url.Parse(ad.ContentURL)
+	if err != nil {
+		return ad, false, fmt.Errorf("invalid contentUrl: %v", err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "file":
+	default:
+		return ad, false, fmt.Errorf("unsupported contentUrl scheme %q", parsed.Scheme)
+	}
+
+	adType := strings.ToLower(strings.TrimSpace(ad.Type))
+	if adType == "" {
+		adType = strings.TrimPrefix(strings.ToLower(path.Ext(parsed.Path)), ".")
+	}
+	canonical, ok := typeAliases[adType]
+	if !ok {
+		return ad, false, fmt.Errorf("unknown type %q", ad.Type)
+	}
+	ad.Type = canonical
+
+	if ad.Duration < 0 {
+		return ad, false, fmt.Errorf("negative duration %d", ad.Duration)
+	}
+	if ad.Size < 0 {
+		return ad, false, fmt.Errorf("negative size %d", ad.Size)
+	}
+	if ad.MaxOfflineAge < 0 {
+		return ad, false, fmt.Errorf("negative maxOfflineAge %d", ad.MaxOfflineAge)
+	}
+	if !ad.StartTime.IsZero() && !ad.EndTime.IsZero() && ad.EndTime.Before(ad.StartTime) {
+		return ad, false, fmt.Errorf("endTime %s is before startTime %s",
+			ad.EndTime.Format(time.RFC3339), ad.StartTime.Format(time.RFC3339))
+	}
+
+	if ad.Checksum != "" {
+		sum := ad.SHA256()
+		if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+			return ad, false, fmt.Errorf("invalid checksum %q", ad.Checksum)
+		}
+		ad.Checksum = sum
+	}
+
+	changed := ad.ID != original.ID || ad.ContentURL != original.ContentURL ||
+		ad.Type != original.Type || ad.Checksum != original.Checksum
+	return ad, changed, nil
+}
@@ -0,0 +1,137 @@
+package cadence
+
+import (
+	"sync"
+	"time"
+)
+
+// Bounds used when the config leaves them unset
+const (
+	DefaultMinInterval = 10 * time.Second
+	DefaultMaxInterval = time.Hour
+)
+
+// Cadence decides when a periodic task runs next
+// It starts from the configured interval, which the server can override with a hint,
+// pull in with a deadline (e.g. a playlist expiry) or push back with Retry-After.
+// Server-supplied values are clamped to [min, max] so a bad response can't stall or flood a screen.
+type Cadence struct {
+	mu       sync.Mutex
+	base     time.Duration
+	min      time.Duration
+	max      time.Duration
+	hint     time.Duration // Server-requested interval, 0 if none
+	deadline time.Time     // Run no later than this, zero if none
+	deferred time.Time     // Run no earlier than this (Retry-After), zero if none
+	next     time.Time     // When the last call to Next scheduled the task
+}
+
+// New creates a cadence around the configured interval; bounds <= 0 use the defaults
+func New(base, min, max time.Duration) *Cadence {
+	if min <= 0 {
+		min = DefaultMinInterval
+	}
+	if max <= 0 {
+		max = DefaultMaxInterval
+	}
+	if max < min {
+		max = min
+	}
+	return &Cadence{base: base, min: min, max: max}
+}
+
+// SetHint sets the interval requested by the server; 0 returns to the configured interval
+func (c *Cadence) SetHint(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if interval <= 0 {
+		c.hint = 0
+		return
+	}
+	c.hint = c.clamp(interval)
+}
+
+// SetDeadline makes the task run no later than t; a zero t clears it
+func (c *Cadence) SetDeadline(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+}
+
+// Defer makes the task wait at least d from now, as asked by a Retry-After header
+// The wait is capped at the maximum interval
+func (c *Cadence) Defer(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > c.max {
+		d = c.max
+	}
+	if until := time.Now().Add(d); until.After(c.deferred) {
+		c.deferred = until
+	}
+}
+
+// Interval returns the interval currently in effect, ignoring deadlines and deferrals
+func (c *Cadence) Interval() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.interval()
+}
+
+// Hinted reports whether the server has requested an interval
+func (c *Cadence) Hinted() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hint > 0
+}
+
+// Next returns how long to wait before the next run and records when that is
+func (c *Cadence) Next() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	wait := c.interval()
+	// An expiry in the past has already been missed; don't poll faster because of it
+	if until := c.deadline.Sub(now); until > 0 && until < wait {
+		wait = until
+		if wait < c.min {
+			wait = c.min
+		}
+	}
+	if until := c.deferred.Sub(now); until > wait {
+		wait = until
+	}
+
+	c.next = now.Add(wait)
+	return wait
+}
+
+// NextRun returns when the task was last scheduled to run, zero before the first call to Next
+func (c *Cadence) NextRun() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.next
+}
+
+// interval returns the hinted interval if there is one, otherwise the configured one
+func (c *Cadence) interval() time.Duration {
+	if c.hint > 0 {
+		return c.hint
+	}
+	return c.base
+}
+
+// clamp limits a server-supplied interval to the bounds
+func (c *Cadence) clamp(d time.Duration) time.Duration {
+	if d < c.min {
+		return c.min
+	}
+	if d > c.max {
+		return c.max
+	}
+	return d
+}
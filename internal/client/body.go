@@ -0,0 +1,113 @@
+package client
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultMaxResponseSize is the default decoded size limit for ad delivery responses (10 MiB)
+const DefaultMaxResponseSize = 10 << 20
+
+// acceptEncoding lists the content codings the client can decode
+// zstd is not offered: the standard library has no decoder for it
+const acceptEncoding = "gzip"
+
+// TransferStats counts ad delivery traffic
+type TransferStats struct {
+	Responses        int       `json:"responses"`        // Response bodies read
+	WireBytes        int64     `json:"wireBytes"`        // Bytes received, before decompression
+	DecodedBytes     int64     `json:"decodedBytes"`     // Bytes after decompression
+	LastWireBytes    int64     `json:"lastWireBytes"`    // Wire size of the most recent response
+	LastDecodedBytes int64     `json:"lastDecodedBytes"` // Decoded size of the most recent response
+	LastEncoding     string    `json:"lastEncoding"`     // Content-Encoding of the most recent response
+	LastResponse     time.Time `json:"lastResponse"`
+}
+
+// AdTransferStats returns byte counts for ad delivery responses read so far
+func (c *Client) AdTransferStats() TransferStats {
+	c.transferMu.Lock()
+	defer c.transferMu.Unlock()
+	return c.transfer
+}
+
+// recordTransfer adds a response body's byte counts to the transfer stats
+func (c *Client) recordTransfer(body *responseBody) {
+	c.transferMu.Lock()
+	defer c.transferMu.Unlock()
+	c.transfer.Responses++
+	c.transfer.WireBytes += body.wire.n
+	c.transfer.DecodedBytes += body.decoded.n
+	c.transfer.LastWireBytes = body.wire.n
+	c.transfer.LastDecodedBytes = body.decoded.n
+	c.transfer.LastEncoding = body.encoding
+	c.transfer.LastResponse = time.Now()
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// responseBody decompresses a response body while enforcing a decoded size limit
+type responseBody struct {
+	what     string
+	limit    int64
+	encoding string
+	wire     *countingReader // Bytes off the network
+	decoded  *countingReader // Bytes after decompression
+}
+
+// newResponseBody wraps resp.Body according to its Content-Encoding
+// The caller still owns resp.Body and must close it
+func newResponseBody(resp *http.Response, what string, limit int64) (*responseBody, error) {
+	b := &responseBody{
+		what:     what,
+		limit:    limit,
+		encoding: strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))),
+		wire:     &countingReader{r: resp.Body},
+	}
+
+	// Reject oversized plain bodies before reading anything
+	if (b.encoding == "" || b.encoding == "identity") && resp.ContentLength > limit {
+		return nil, &ResponseTooLargeError{What: what, Limit: limit}
+	}
+
+	switch b.encoding {
+	case "", "identity":
+		b.encoding = "identity"
+		b.decoded = &countingReader{r: b.wire}
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(b.wire)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		b.decoded = &countingReader{r: gz}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", b.encoding)
+	}
+
+	return b, nil
+}
+
+// decode streams the body into v, failing with ResponseTooLargeError past the limit
+func (b *responseBody) decode(v interface{}) error {
+	// Allow one byte past the limit so overflow can be told apart from an exact fit
+	limited := io.LimitReader(b.decoded, b.limit+1)
+	err := json.NewDecoder(limited).Decode(v)
+	if b.decoded.n > b.limit {
+		return &ResponseTooLargeError{What: b.what, Limit: b.limit}
+	}
+	return err
+}
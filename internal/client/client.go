@@ -2,43 +2,99 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mnemoCast-client/internal/models"
+	"mnemoCast-client/internal/retry"
+	"mnemoCast-client/pkg/signing"
 	"net/http"
+	"sync"
 	"time"
 )
 
 // Client handles communication with the ad server
 type Client struct {
-	baseURL    string
+	endpoints  *endpointPool // Ad server endpoints and which one is active
 	screenID   string
 	passkey    string
 	httpClient *http.Client
+	retryPolicy *retry.Policy
+	signRequests bool // Send HMAC signatures instead of the raw passkey
+	streamIdleTimeout time.Duration // Silence after which the event stream is reopened
+
+	maxResponseSize int64 // Decoded size limit for ad delivery responses
+	transferMu      sync.Mutex
+	transfer        TransferStats
+
+	// Bearer session issued on Connect
+	sessionMu        sync.RWMutex
+	session          *models.SessionToken
+	refreshMu        sync.Mutex // Serializes token refreshes
+	onSessionUpdated func(refreshToken string)
 }
 
 // NewClient creates a new ad server client with screen ID and passkey
 func NewClient(baseURL string, screenID string, passkey string) *Client {
 	return &Client{
-		baseURL:  baseURL,
+		endpoints: newEndpointPool([]models.ServerEndpoint{{URL: baseURL}}, DefaultFailBackDelay),
 		screenID: screenID,
 		passkey:  passkey,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		retryPolicy:     retry.DefaultPolicy(),
+		streamIdleTimeout: defaultStreamIdleTimeout,
+		maxResponseSize: DefaultMaxResponseSize,
 	}
 }
 
+// SetMaxResponseSize sets the largest ad delivery response accepted, in decoded bytes
+// Values <= 0 restore the default
+func (c *Client) SetMaxResponseSize(size int64) {
+	if size <= 0 {
+		size = DefaultMaxResponseSize
+	}
+	c.maxResponseSize = size
+}
+
+// SetTransport sets the HTTP transport (TLS, proxy, dial settings) and total request timeout
+// The event stream shares the transport but is not subject to the timeout
+func (c *Client) SetTransport(transport http.RoundTripper, timeout time.Duration) {
+	c.httpClient = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+}
+
+// RetryPolicy returns the retry policy used for all requests
+func (c *Client) RetryPolicy() *retry.Policy {
+	return c.retryPolicy
+}
+
+// SetRetryPolicy replaces the retry policy used for all requests
+func (c *Client) SetRetryPolicy(policy *retry.Policy) {
+	if policy != nil {
+		c.retryPolicy = policy
+	}
+}
+
+// SetSigning switches between HMAC request signing and sending the raw passkey header
+func (c *Client) SetSigning(enabled bool) {
+	c.signRequests = enabled
+}
+
 // SetCredentials updates the screen ID and passkey
 func (c *Client) SetCredentials(screenID string, passkey string) {
 	c.screenID = screenID
 	c.passkey = passkey
 }
 
-// createRequest creates an HTTP request with authentication headers
-func (c *Client) createRequest(method, url string, body interface{}) (*http.Request, error) {
+// createRequest creates an HTTP request bound to ctx with authentication headers
+func (c *Client) createRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		jsonData, err := json.Marshal(body)
@@ -48,13 +104,48 @@ func (c *Client) createRequest(method, url string, body interface{}) (*http.Requ
 		bodyReader = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// authorize sets the authentication headers on req
+// Signed requests carry a single-use nonce, so this must run again before every retry.
+// A valid session token takes precedence over the passkey except for passkey-only requests
+func (c *Client) authorize(req *http.Request) error {
+	// Start clean; a retry may switch between schemes
+	for _, header := range authHeaders {
+		req.Header.Del(header)
+	}
+
+	if !passkeyOnly(req.Context()) {
+		if token := c.accessToken(); token != "" {
+			req.Header.Set("X-Screen-Id", c.screenID)
+			req.Header.Set("Authorization", "Bearer "+token)
+			return nil
+		}
+	}
+
+	if c.signRequests && c.screenID != "" && c.passkey != "" {
+		body, err := signing.ReadBody(req)
+		if err != nil {
+			return fmt.Errorf("failed to read request body for signing: %w", err)
+		}
+		if err := signing.Sign(req, c.screenID, c.passkey, body, time.Now()); err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+		return nil
+	}
+
 	// Set authentication headers (server expects X-Screen-Id and X-Screen-Passkey)
 	if c.screenID != "" {
 		req.Header.Set("X-Screen-Id", c.screenID)  // Server expects X-Screen-Id (not X-Screen-ID)
@@ -62,74 +153,163 @@ func (c *Client) createRequest(method, url string, body interface{}) (*http.Requ
 	if c.passkey != "" {
 		req.Header.Set("X-Screen-Passkey", c.passkey)  // Server expects X-Screen-Passkey (not X-Passkey)
 	}
+	return nil
+}
 
-	return req, nil
+// doRequest executes an HTTP request using the client's retry policy
+// Transport errors and retryable statuses (5xx, 429) are retried; any other response is returned as-is.
+// A bearer token rejected with 401 is refreshed once and the request replayed.
+// Cancelling the request context aborts both the in-flight call and any pending retry wait
+func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !passkeyOnly(ctx) {
+		c.ensureSession(ctx)
+	}
+
+	resp, err := c.sendWithRetry(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && req.Header.Get("Authorization") != "" {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+		resp.Body.Close()
+
+		log.Printf("[%s] [AUTH] Access token rejected, refreshing session", time.Now().Format("15:04:05.000"))
+		c.refreshSession(ctx, true)
+		return c.sendWithRetry(req)
+	}
+
+	return resp, nil
 }
 
-// doRequest executes an HTTP request with retry logic
-func (c *Client) doRequest(req *http.Request, maxRetries int, retryDelay time.Duration) (*http.Response, error) {
-	var lastErr error
+// sendWithRetry sends req until it succeeds or the retry policy gives up
+func (c *Client) sendWithRetry(req *http.Request) (*http.Response, error) {
+	policy := c.retryPolicy
+	var resp *http.Response
+
+	err := policy.Do(req.Context(), func(attempt int) error {
+		// Rewind the body so every attempt sends the same payload
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return retry.Permanent(fmt.Errorf("failed to rewind request body: %w", err))
+			}
+			req.Body = body
+		}
+		// Follow a failover, then re-authorize: signatures cover the path and need a fresh
+		// nonce, and the session token may have changed
+		target := c.endpoints.route(req)
+		if err := c.authorize(req); err != nil {
+			return retry.Permanent(err)
+		}
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			time.Sleep(retryDelay * time.Duration(attempt)) // Exponential backoff
+		r, err := c.httpClient.Do(req)
+		if err != nil {
+			if req.Context().Err() == nil {
+				c.endpoints.reportFailure(target, err)
+			}
+			return err
+		}
+		if endpointDown(r) {
+			c.endpoints.reportFailure(target, fmt.Errorf("%s returned %d", req.URL.Path, r.StatusCode))
+		} else {
+			c.endpoints.reportSuccess(target)
 		}
 
-		resp, err := c.httpClient.Do(req)
-		if err == nil {
-			return resp, nil
+		// Hand the last response back to the caller so it can report the status
+		if retry.RetryableStatus(r.StatusCode) && attempt < policy.MaxRetries {
+			statusErr := newStatusError(r, req.URL.Path)
+			r.Body.Close()
+			return statusErr
 		}
 
-		lastErr = err
+		resp = r
+		return nil
+	}, func(retryNum int, delay time.Duration, err error) {
+		log.Printf("[%s] [RETRY] %s %s failed (%v), retry %d/%d in %v",
+			time.Now().Format("15:04:05.000"), req.Method, req.URL.Path, err, retryNum, policy.MaxRetries, delay)
+	})
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, fmt.Errorf("request cancelled: %w", err)
+		}
+		var deferred *retry.RetryAfterError
+		if errors.As(err, &deferred) {
+			return nil, fmt.Errorf("request deferred: %w", err)
+		}
+		return nil, fmt.Errorf("request failed after %d attempts: %w", policy.MaxRetries+1, err)
 	}
 
-	return nil, fmt.Errorf("request failed after %d attempts: %w", maxRetries+1, lastErr)
+	return resp, nil
 }
 
 // Connect authenticates with the ad server using screen ID and passkey
 // This replaces the registration flow - screen is pre-registered on server
 func (c *Client) Connect() (*models.Screen, error) {
-	url := fmt.Sprintf("%s/api/v1/screens/%s/connect", c.baseURL, c.screenID)
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext is like Connect but aborts the request and any retry waits when ctx is cancelled
+func (c *Client) ConnectContext(ctx context.Context) (*models.Screen, error) {
+	url := fmt.Sprintf("%s/api/v1/screens/%s/connect", c.endpoints.activeURL(), c.screenID)
 
 	// Create connection request (empty body, auth via headers)
-	req, err := c.createRequest("POST", url, nil)
+	// Connect always proves the passkey; it is what issues the session token
+	req, err := c.createRequest(withPasskeyAuth(ctx), "POST", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	// Execute request with retry
-	resp, err := c.doRequest(req, 3, 2*time.Second)
+	resp, err := c.doRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("connection failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check status code
-	if resp.StatusCode == http.StatusUnauthorized {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("authentication failed: invalid screen ID or passkey - %s", string(body))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("connection failed: %w", newStatusError(resp, "screen "+c.screenID))
 	}
-	if resp.StatusCode == http.StatusNotFound {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("screen not found: screen ID %s does not exist - %s", c.screenID, string(body))
+
+	// Parse response; servers with session support add token fields next to the screen
+	var connectResp struct {
+		models.Screen
+		tokenResponse
 	}
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("connection failed with status %d: %s", resp.StatusCode, string(body))
+	if err := json.NewDecoder(resp.Body).Decode(&connectResp); err != nil {
+		return nil, &DecodeError{What: "connection response", Err: err}
 	}
 
-	// Parse response
-	var screen models.Screen
-	if err := json.NewDecoder(resp.Body).Decode(&screen); err != nil {
-		return nil, fmt.Errorf("failed to parse connection response: %w", err)
+	if connectResp.AccessToken != "" {
+		c.setSession(connectResp.tokenResponse.toSession(time.Now()))
+		log.Printf("[%s] [AUTH] Session established (expires: %s)", 
+			time.Now().Format("15:04:05.000"), c.sessionExpiry().Format(time.RFC3339))
 	}
 
+	screen := connectResp.Screen
 	return &screen, nil
 }
 
 // Heartbeat sends a heartbeat to the ad server using PUT method
 func (c *Client) Heartbeat(screenID string) error {
-	url := fmt.Sprintf("%s/api/v1/screens/%s/heartbeat", c.baseURL, screenID)
+	return c.HeartbeatContext(context.Background(), screenID)
+}
+
+// HeartbeatContext is like Heartbeat but aborts the request and any retry waits when ctx is cancelled
+func (c *Client) HeartbeatContext(ctx context.Context, screenID string) error {
+	_, err := c.HeartbeatWithResponse(ctx, screenID)
+	return err
+}
+
+// maxHeartbeatResponseSize caps how much of a heartbeat response is read
+const maxHeartbeatResponseSize = 64 << 10
+
+// HeartbeatWithResponse sends a heartbeat and returns the server's response, which may carry scheduling hints
+// An empty or unparsable body on success is not an error; the response is then empty
+func (c *Client) HeartbeatWithResponse(ctx context.Context, screenID string) (*models.HeartbeatResponse, error) {
+	url := fmt.Sprintf("%s/api/v1/screens/%s/heartbeat", c.endpoints.activeURL(), screenID)
 	requestTime := time.Now()
 
 	// Create heartbeat request
@@ -141,61 +321,103 @@ func (c *Client) Heartbeat(screenID string) error {
 	log.Printf("[%s] [REQUEST] Sending heartbeat request to: %s", requestTime.Format("15:04:05.000"), url)
 	log.Printf("[%s] [REQUEST] Method: PUT | Screen ID: %s", requestTime.Format("15:04:05.000"), screenID)
 
-	req, err := c.createRequest("PUT", url, request)
+	req, err := c.createRequest(ctx, "PUT", url, request)
 	if err != nil {
 		log.Printf("[%s] [ERROR] Failed to create heartbeat request: %v", time.Now().Format("15:04:05.000"), err)
-		return err
+		return nil, err
 	}
 
 	// Execute request with retry
-	resp, err := c.doRequest(req, 3, 2*time.Second)
+	resp, err := c.doRequest(req)
 	responseTime := time.Now()
 	duration := responseTime.Sub(requestTime)
 
 	if err != nil {
 		log.Printf("[%s] [ERROR] Heartbeat request failed after %v: %v", responseTime.Format("15:04:05.000"), duration, err)
-		return fmt.Errorf("heartbeat failed: %w", err)
+		return nil, fmt.Errorf("heartbeat failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("[%s] [ERROR] Heartbeat failed: Status %d | Duration: %v | Response: %s", 
-			responseTime.Format("15:04:05.000"), resp.StatusCode, duration, string(body))
-		return fmt.Errorf("heartbeat failed with status %d: %s", resp.StatusCode, string(body))
+		statusErr := newStatusError(resp, "screen "+screenID)
+		log.Printf("[%s] [ERROR] Heartbeat failed: Status %d | Duration: %v | Error: %v", 
+			responseTime.Format("15:04:05.000"), resp.StatusCode, duration, statusErr)
+		return nil, fmt.Errorf("heartbeat failed: %w", statusErr)
+	}
+
+	var heartbeatResp models.HeartbeatResponse
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxHeartbeatResponseSize)).Decode(&heartbeatResp); err != nil && err != io.EOF {
+			log.Printf("[%s] [WARN] Ignoring unparsable heartbeat response: %v", responseTime.Format("15:04:05.000"), err)
+			heartbeatResp = models.HeartbeatResponse{}
+		}
 	}
 
 	log.Printf("[%s] [OK] Heartbeat successful: Status %d | Duration: %v | Path: %s", 
-		responseTime.Format("15:04:05.000"), resp.StatusCode, duration, url)
-	return nil
+		responseTime.Format("15:04:05.000"), resp.StatusCode, duration, req.URL)
+	return &heartbeatResp, nil
 }
 
 // GetAds fetches ads from the ad server for the screen
 func (c *Client) GetAds(screenID string) (*models.AdDeliveryResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/screens/%s/ads/deliver", c.baseURL, screenID)
+	return c.GetAdsContext(context.Background(), screenID)
+}
+
+// GetAdsContext is like GetAds but aborts the request and any retry waits when ctx is cancelled
+func (c *Client) GetAdsContext(ctx context.Context, screenID string) (*models.AdDeliveryResponse, error) {
+	adResponse, _, err := c.GetAdsConditional(ctx, screenID, nil)
+	return adResponse, err
+}
+
+// GetAdsConditional fetches ads only if they changed since the response described by validators
+// It returns ErrNotModified on 304, otherwise the ads together with the validators of this response
+func (c *Client) GetAdsConditional(ctx context.Context, screenID string, validators *models.CacheValidators) (*models.AdDeliveryResponse, *models.CacheValidators, error) {
+	url := fmt.Sprintf("%s/api/v1/screens/%s/ads/deliver", c.endpoints.activeURL(), screenID)
 	requestTime := time.Now()
 
 	log.Printf("[%s] [REQUEST] Fetching ads from: %s", requestTime.Format("15:04:05.000"), url)
 	log.Printf("[%s] [REQUEST] Method: GET | Screen ID: %s", requestTime.Format("15:04:05.000"), screenID)
 
-	req, err := c.createRequest("GET", url, nil)
+	req, err := c.createRequest(ctx, "GET", url, nil)
 	if err != nil {
 		log.Printf("[%s] [ERROR] Failed to create ads request: %v", time.Now().Format("15:04:05.000"), err)
-		return nil, err
+		return nil, nil, err
+	}
+	// Setting Accept-Encoding ourselves disables Go's transparent gzip so wire bytes can be counted
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	// Ask the server to skip the body if nothing changed since the last response
+	if validators != nil {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
 	}
 
 	// Execute request with retry
-	resp, err := c.doRequest(req, 3, 2*time.Second)
+	resp, err := c.doRequest(req)
 	responseTime := time.Now()
 	duration := responseTime.Sub(requestTime)
 
 	if err != nil {
 		log.Printf("[%s] [ERROR] Ads request failed after %v: %v", responseTime.Format("15:04:05.000"), duration, err)
-		return nil, fmt.Errorf("failed to fetch ads: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch ads: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		log.Printf("[%s] [INFO] Ads unchanged (Status 304) | Duration: %v", responseTime.Format("15:04:05.000"), duration)
+		return nil, nil, ErrNotModified
+	}
+
+	newValidators := &models.CacheValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
 	// Check status code
 	if resp.StatusCode == http.StatusNoContent {
 		// No ads available
@@ -203,35 +425,99 @@ func (c *Client) GetAds(screenID string) (*models.AdDeliveryResponse, error) {
 		return &models.AdDeliveryResponse{
 			Ads:      []models.Ad{},
 			UpdatedAt: time.Now(),
-		}, nil
+		}, newValidators, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("[%s] [ERROR] Ads request failed: Status %d | Duration: %v | Response: %s", 
-			responseTime.Format("15:04:05.000"), resp.StatusCode, duration, string(body))
-		return nil, fmt.Errorf("failed to fetch ads with status %d: %s", resp.StatusCode, string(body))
+		statusErr := newStatusError(resp, "screen "+screenID)
+		log.Printf("[%s] [ERROR] Ads request failed: Status %d | Duration: %v | Error: %v", 
+			responseTime.Format("15:04:05.000"), resp.StatusCode, duration, statusErr)
+		return nil, nil, fmt.Errorf("failed to fetch ads: %w", statusErr)
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Decode the body as it streams in; compressed and decoded sizes are counted for stats
+	body, err := newResponseBody(resp, "ads response", c.maxResponseSize)
 	if err != nil {
-		log.Printf("[%s] [ERROR] Failed to read ads response body: %v", responseTime.Format("15:04:05.000"), err)
-		return nil, fmt.Errorf("failed to read ads response: %w", err)
+		log.Printf("[%s] [ERROR] Failed to read ads response: %v", responseTime.Format("15:04:05.000"), err)
+		return nil, nil, fmt.Errorf("failed to read ads response: %w", err)
 	}
 
-	// Log the raw JSON response from server
-	log.Printf("[%s] [RESPONSE] Ads JSON Response from Server:\n%s", responseTime.Format("15:04:05.000"), string(body))
-
-	// Parse response
-	var adResponse models.AdDeliveryResponse
-	if err := json.Unmarshal(body, &adResponse); err != nil {
+	var delivery deliveryBody
+	err = body.decode(&delivery)
+	c.recordTransfer(body)
+	if err != nil {
+		var tooLarge *ResponseTooLargeError
+		if errors.As(err, &tooLarge) {
+			log.Printf("[%s] [ERROR] Ads response too large: %v", responseTime.Format("15:04:05.000"), err)
+			return nil, nil, fmt.Errorf("failed to fetch ads: %w", err)
+		}
 		log.Printf("[%s] [ERROR] Failed to parse ads response: %v", responseTime.Format("15:04:05.000"), err)
-		return nil, fmt.Errorf("failed to parse ads response: %w", err)
+		return nil, nil, &DecodeError{What: "ads response", Err: err}
+	}
+
+	adResponse, err := delivery.open()
+	if err != nil {
+		log.Printf("[%s] [ERROR] Failed to parse signed ads response: %v", responseTime.Format("15:04:05.000"), err)
+		return nil, nil, &DecodeError{What: "ads response", Err: err}
 	}
 
+	log.Printf("[%s] [RESPONSE] Ads response: %d bytes received, %d bytes decoded (encoding: %s)",
+		responseTime.Format("15:04:05.000"), body.wire.n, body.decoded.n, body.encoding)
+
 	log.Printf("[%s] [OK] Ads fetched successfully: %d ads | Duration: %v | Path: %s", 
-		responseTime.Format("15:04:05.000"), len(adResponse.Ads), duration, url)
+		responseTime.Format("15:04:05.000"), len(adResponse.Ads), duration, req.URL)
+	return adResponse, newValidators, nil
+}
+
+// ReportRejectedAds tells the server which delivered ads the screen rejected and why
+func (c *Client) ReportRejectedAds(ctx context.Context, screenID string, report *models.AdRejectionReport) error {
+	url := fmt.Sprintf("%s/api/v1/screens/%s/ads/rejections", c.endpoints.activeURL(), screenID)
+
+	req, err := c.createRequest(ctx, "POST", url, report)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return fmt.Errorf("ad rejection report failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil
+	}
+	return fmt.Errorf("ad rejection report failed: %w", newStatusError(resp, "screen "+screenID))
+}
+
+// deliveryBody is an ad delivery response that may instead be a signed envelope
+type deliveryBody struct {
+	models.AdDeliveryResponse
+	signing.Envelope
+}
+
+// open returns the playlist, unwrapping it from its envelope if it was signed
+// The signature is attached unverified; checking it against the pinned key is up to the caller
+func (b *deliveryBody) open() (*models.AdDeliveryResponse, error) {
+	if !b.Envelope.IsEnvelope() {
+		return &b.AdDeliveryResponse, nil
+	}
+
+	payload, signature, err := b.Envelope.Decode()
+	if err != nil {
+		return nil, err
+	}
+	var adResponse models.AdDeliveryResponse
+	if err := json.Unmarshal(payload, &adResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse signed playlist: %w", err)
+	}
+	adResponse.Signature = &models.PlaylistSignature{
+		Payload:   payload,
+		Signature: signature,
+		KeyID:     b.Envelope.KeyID,
+	}
 	return &adResponse, nil
 }
 
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mnemoCast-client/internal/models"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthPath is requested on every endpoint by the health checks
+// Any response below 500 counts as healthy, so servers without the route still pass
+const healthPath = "/health"
+
+// DefaultFailBackDelay is how long a preferred endpoint must stay healthy before the client returns to it
+const DefaultFailBackDelay = 2 * time.Minute
+
+// EndpointStatus describes one ad server endpoint
+type EndpointStatus struct {
+	URL          string    `json:"url"`
+	Priority     int       `json:"priority"`
+	Active       bool      `json:"active"`       // Requests currently go to this endpoint
+	Healthy      bool      `json:"healthy"`      // Last request or health check succeeded
+	HealthySince time.Time `json:"healthySince"` // Start of the current healthy streak
+	LastCheck    time.Time `json:"lastCheck"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+// endpoint is one ad server endpoint and its health
+type endpoint struct {
+	url          string
+	priority     int
+	healthy      bool
+	healthySince time.Time
+	lastCheck    time.Time
+	lastError    error
+}
+
+// endpointPool picks the endpoint requests go to
+// Failover is sticky: after switching away, the client only returns to a preferred endpoint once
+// it has passed health checks for failBackAfter, so a flapping server doesn't bounce the screen.
+type endpointPool struct {
+	mu            sync.RWMutex
+	endpoints     []*endpoint // Highest priority first, config order within a priority
+	active        *endpoint
+	failBackAfter time.Duration
+	switches      int
+}
+
+// newEndpointPool creates a pool; every endpoint starts healthy and the preferred one active
+func newEndpointPool(endpoints []models.ServerEndpoint, failBackAfter time.Duration) *endpointPool {
+	now := time.Now()
+	pool := &endpointPool{failBackAfter: failBackAfter}
+	for _, configured := range endpoints {
+		pool.endpoints = append(pool.endpoints, &endpoint{
+			url:          strings.TrimRight(configured.URL, "/"),
+			priority:     configured.Priority,
+			healthy:      true,
+			healthySince: now,
+		})
+	}
+	sort.SliceStable(pool.endpoints, func(i, j int) bool {
+		return pool.endpoints[i].priority > pool.endpoints[j].priority
+	})
+	if len(pool.endpoints) > 0 {
+		pool.active = pool.endpoints[0]
+	}
+	return pool
+}
+
+// activeURL returns the base URL requests currently go to
+func (p *endpointPool) activeURL() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.active == nil {
+		return ""
+	}
+	return p.active.url
+}
+
+// route points req at the active endpoint and returns it
+// Requests built against another endpoint keep their path and query, so retries follow a failover
+func (p *endpointPool) route(req *http.Request) *endpoint {
+	p.mu.RLock()
+	active := p.active
+	var from *endpoint
+	target := req.URL.String()
+	for _, candidate := range p.endpoints {
+		if strings.HasPrefix(target, candidate.url) && (from == nil || len(candidate.url) > len(from.url)) {
+			from = candidate
+		}
+	}
+	p.mu.RUnlock()
+
+	if active == nil || from == nil || from == active {
+		return from
+	}
+	rewritten, err := url.Parse(active.url + strings.TrimPrefix(target, from.url))
+	if err != nil {
+		return from
+	}
+	req.URL = rewritten
+	req.Host = rewritten.Host
+	return active
+}
+
+// endpointDown reports whether a response means the endpoint itself is unavailable
+// Only gateway errors and 503 count; a 503 with Retry-After is the server asking for a backoff,
+// and other 5xx errors usually concern the one request, so neither moves the screen elsewhere
+func endpointDown(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Header.Get("Retry-After") == ""
+	default:
+		return false
+	}
+}
+
+// reportSuccess records that an endpoint answered
+func (p *endpointPool) reportSuccess(e *endpoint) {
+	if e == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.markLocked(e, nil, time.Now())
+}
+
+// reportFailure records that an endpoint was unreachable or failing, failing over if it was active
+func (p *endpointPool) reportFailure(e *endpoint, err error) {
+	if e == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.markLocked(e, err, time.Now())
+	if e == p.active {
+		p.failoverLocked(err)
+	}
+}
+
+// markLocked updates an endpoint's health; callers must hold p.mu for writing
+func (p *endpointPool) markLocked(e *endpoint, err error, now time.Time) {
+	e.lastCheck = now
+	e.lastError = err
+	if err != nil {
+		e.healthy = false
+		return
+	}
+	if !e.healthy {
+		e.healthy = true
+		e.healthySince = now
+	}
+}
+
+// failoverLocked switches away from the active endpoint
+// The most preferred healthy endpoint is chosen; if none is known to be healthy the next one in
+// order is tried, so every endpoint gets a turn. Callers must hold p.mu for writing
+func (p *endpointPool) failoverLocked(reason error) {
+	if len(p.endpoints) < 2 {
+		return
+	}
+
+	var next *endpoint
+	for _, candidate := range p.endpoints {
+		if candidate != p.active && candidate.healthy {
+			next = candidate
+			break
+		}
+	}
+	if next == nil {
+		for i, candidate := range p.endpoints {
+			if candidate == p.active {
+				next = p.endpoints[(i+1)%len(p.endpoints)]
+				break
+			}
+		}
+	}
+	p.switchLocked(next, fmt.Sprintf("failing over: %v", reason))
+}
+
+// failBackLocked returns to a more preferred endpoint that has been healthy for long enough
+// Callers must hold p.mu for writing
+func (p *endpointPool) failBackLocked(now time.Time) {
+	for _, candidate := range p.endpoints {
+		if candidate == p.active {
+			return
+		}
+		if candidate.healthy && now.Sub(candidate.healthySince) >= p.failBackAfter {
+			p.switchLocked(candidate, fmt.Sprintf("failing back, healthy for %v", now.Sub(candidate.healthySince).Round(time.Second)))
+			return
+		}
+	}
+}
+
+// switchLocked makes next the active endpoint; callers must hold p.mu for writing
+func (p *endpointPool) switchLocked(next *endpoint, reason string) {
+	if next == nil || next == p.active {
+		return
+	}
+	log.Printf("[%s] [FAILOVER] Ad server endpoint %s -> %s (%s)",
+		time.Now().Format("15:04:05.000"), p.active.url, next.url, reason)
+	p.active = next
+	p.switches++
+}
+
+// status returns the state of every endpoint, most preferred first
+func (p *endpointPool) status() []EndpointStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		status := EndpointStatus{
+			URL:          e.url,
+			Priority:     e.priority,
+			Active:       e == p.active,
+			Healthy:      e.healthy,
+			HealthySince: e.healthySince,
+			LastCheck:    e.lastCheck,
+		}
+		if !e.healthy {
+			status.HealthySince = time.Time{}
+		}
+		if e.lastError != nil {
+			status.LastError = e.lastError.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// SetEndpoints replaces the ad server endpoints the client fails over between
+// failBackAfter <= 0 fails back at the first healthy check. Must be called before the client is used
+func (c *Client) SetEndpoints(endpoints []models.ServerEndpoint, failBackAfter time.Duration) {
+	if len(endpoints) == 0 {
+		return
+	}
+	if failBackAfter < 0 {
+		failBackAfter = 0
+	}
+	c.endpoints = newEndpointPool(endpoints, failBackAfter)
+}
+
+// ActiveEndpoint returns the base URL requests currently go to
+func (c *Client) ActiveEndpoint() string {
+	return c.endpoints.activeURL()
+}
+
+// EndpointStatus returns the health of every ad server endpoint, most preferred first
+func (c *Client) EndpointStatus() []EndpointStatus {
+	return c.endpoints.status()
+}
+
+// EndpointSwitches returns how many times the client has changed endpoints
+func (c *Client) EndpointSwitches() int {
+	c.endpoints.mu.RLock()
+	defer c.endpoints.mu.RUnlock()
+	return c.endpoints.switches
+}
+
+// StartHealthChecks checks every endpoint on interval until ctx is cancelled
+// Health checks are what let the client fail back; with a single endpoint they only update its status
+func (c *Client) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.CheckEndpoints(ctx)
+			}
+		}
+	}()
+}
+
+// CheckEndpoints health checks every endpoint once, then fails over or back as needed
+func (c *Client) CheckEndpoints(ctx context.Context) {
+	c.endpoints.mu.RLock()
+	endpoints := make([]*endpoint, len(c.endpoints.endpoints))
+	copy(endpoints, c.endpoints.endpoints)
+	c.endpoints.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	results := make([]error, len(endpoints))
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func(i int, e *endpoint) {
+			defer wg.Done()
+			results[i] = c.checkEndpoint(ctx, e)
+		}(i, e)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	pool := c.endpoints
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	now := time.Now()
+	for i, e := range endpoints {
+		pool.markLocked(e, results[i], now)
+	}
+	if !pool.active.healthy {
+		pool.failoverLocked(pool.active.lastError)
+	}
+	pool.failBackLocked(now)
+}
+
+// checkEndpoint requests the health path of one endpoint without retries
+func (c *Client) checkEndpoint(ctx context.Context, e *endpoint) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+healthPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"mnemoCast-client/internal/models"
+	"mnemoCast-client/internal/retry"
+)
+
+// testServer is an ad server whose health can be changed while a test runs
+type testServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	status     int
+	retryAfter string
+	paths      []string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	server := &testServer{status: http.StatusOK}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		status, retryAfter := server.status, server.retryAfter
+		server.paths = append(server.paths, r.URL.Path)
+		server.mu.Unlock()
+
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *testServer) set(status int, retryAfter string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	s.retryAfter = retryAfter
+}
+
+// requests returns the paths requested other than health checks
+func (s *testServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var paths []string
+	for _, path := range s.paths {
+		if path != healthPath {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func newTestClient(retries int, failBackAfter time.Duration, endpoints ...models.ServerEndpoint) *Client {
+	c := NewClient(endpoints[0].URL, "screen-1", "passkey")
+	c.SetRetryPolicy(retry.NewPolicy(retries, time.Millisecond, 2*time.Millisecond))
+	c.SetEndpoints(endpoints, failBackAfter)
+	return c
+}
+
+func TestEndpointsOrderedByPriority(t *testing.T) {
+	low, high, mid := newTestServer(t), newTestServer(t), newTestServer(t)
+	c := newTestClient(0, time.Minute,
+		models.ServerEndpoint{URL: low.URL},
+		models.ServerEndpoint{URL: high.URL, Priority: 10},
+		models.ServerEndpoint{URL: mid.URL, Priority: 5},
+	)
+
+	if got := c.ActiveEndpoint(); got != high.URL {
+		t.Fatalf("active endpoint = %s, want highest priority %s", got, high.URL)
+	}
+	status := c.EndpointStatus()
+	want := []string{high.URL, mid.URL, low.URL}
+	for i, url := range want {
+		if status[i].URL != url {
+			t.Errorf("endpoint %d = %s, want %s", i, status[i].URL, url)
+		}
+	}
+	if !status[0].Active || status[1].Active || status[2].Active {
+		t.Errorf("only the first endpoint should be active: %+v", status)
+	}
+}
+
+func TestFailoverOnConnectionError(t *testing.T) {
+	primary, backup := newTestServer(t), newTestServer(t)
+	c := newTestClient(2, time.Minute,
+		models.ServerEndpoint{URL: primary.URL, Priority: 10},
+		models.ServerEndpoint{URL: backup.URL},
+	)
+	primary.Close()
+
+	if _, err := c.HeartbeatWithResponse(context.Background(), "screen-1"); err != nil {
+		t.Fatalf("heartbeat should succeed on the backup: %v", err)
+	}
+	if got := c.ActiveEndpoint(); got != backup.URL {
+		t.Fatalf("active endpoint = %s, want backup %s", got, backup.URL)
+	}
+	// The retry built against the primary was rewritten onto the backup with the same path
+	if got := backup.requests(); len(got) != 1 || got[0] != "/api/v1/screens/screen-1/heartbeat" {
+		t.Errorf("backup requests = %v, want the heartbeat once", got)
+	}
+	if got := c.EndpointSwitches(); got != 1 {
+		t.Errorf("switches = %d, want 1", got)
+	}
+}
+
+func TestFailoverOnGatewayErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			primary, backup := newTestServer(t), newTestServer(t)
+			primary.set(status, "")
+			c := newTestClient(1, time.Minute,
+				models.ServerEndpoint{URL: primary.URL, Priority: 10},
+				models.ServerEndpoint{URL: backup.URL},
+			)
+
+			if _, err := c.HeartbeatWithResponse(context.Background(), "screen-1"); err != nil {
+				t.Fatalf("heartbeat should succeed on the backup: %v", err)
+			}
+			if got := c.ActiveEndpoint(); got != backup.URL {
+				t.Fatalf("active endpoint = %s, want backup %s", got, backup.URL)
+			}
+			if got := len(primary.requests()); got != 1 {
+				t.Errorf("primary requests = %d, want 1 before failing over", got)
+			}
+			if got := len(backup.requests()); got != 1 {
+				t.Errorf("backup requests = %d, want the retry", got)
+			}
+		})
+	}
+}
+
+func TestNoFailoverOnBackoffOrRequestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+	}{
+		{"503 with Retry-After", http.StatusServiceUnavailable, "30"},
+		{"500", http.StatusInternalServerError, ""},
+		{"429", http.StatusTooManyRequests, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			primary, backup := newTestServer(t), newTestServer(t)
+			primary.set(test.status, test.retryAfter)
+			c := newTestClient(0, time.Minute,
+				models.ServerEndpoint{URL: primary.URL, Priority: 10},
+				models.ServerEndpoint{URL: backup.URL},
+			)
+
+			if _, err := c.HeartbeatWithResponse(context.Background(), "screen-1"); err == nil {
+				t.Fatal("heartbeat should fail")
+			}
+			if got := c.ActiveEndpoint(); got != primary.URL {
+				t.Errorf("active endpoint = %s, want to stay on primary %s", got, primary.URL)
+			}
+			if got := c.EndpointSwitches(); got != 0 {
+				t.Errorf("switches = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestStickyFailoverAndFailBack(t *testing.T) {
+	primary, backup := newTestServer(t), newTestServer(t)
+	failBackAfter := 200 * time.Millisecond
+	c := newTestClient(1, failBackAfter,
+		models.ServerEndpoint{URL: primary.URL, Priority: 10},
+		models.ServerEndpoint{URL: backup.URL},
+	)
+	ctx := context.Background()
+
+	primary.set(http.StatusServiceUnavailable, "")
+	if _, err := c.HeartbeatWithResponse(ctx, "screen-1"); err != nil {
+		t.Fatalf("heartbeat should succeed on the backup: %v", err)
+	}
+	if got := c.ActiveEndpoint(); got != backup.URL {
+		t.Fatalf("active endpoint = %s, want backup %s", got, backup.URL)
+	}
+
+	// A failed health check keeps the primary out
+	c.CheckEndpoints(ctx)
+	if status := c.EndpointStatus(); status[0].Healthy {
+		t.Errorf("primary should be unhealthy after a failed check: %+v", status[0])
+	}
+
+	// Recovered, but not for failBackAfter yet: stay on the backup
+	primary.set(http.StatusOK, "")
+	c.CheckEndpoints(ctx)
+	if got := c.ActiveEndpoint(); got != backup.URL {
+		t.Fatalf("failed back before the primary was healthy for %v", failBackAfter)
+	}
+	if _, err := c.HeartbeatWithResponse(ctx, "screen-1"); err != nil {
+		t.Fatalf("heartbeat on the backup: %v", err)
+	}
+	if got := len(backup.requests()); got != 2 {
+		t.Errorf("backup requests = %d, want 2 while sticky", got)
+	}
+
+	time.Sleep(failBackAfter)
+	c.CheckEndpoints(ctx)
+	if got := c.ActiveEndpoint(); got != primary.URL {
+		t.Fatalf("active endpoint = %s, want fail-back to %s", got, primary.URL)
+	}
+	if got := c.EndpointSwitches(); got != 2 {
+		t.Errorf("switches = %d, want 2", got)
+	}
+}
+
+func TestHealthCheckFailover(t *testing.T) {
+	primary, backup := newTestServer(t), newTestServer(t)
+	c := newTestClient(0, time.Minute,
+		models.ServerEndpoint{URL: primary.URL, Priority: 10},
+		models.ServerEndpoint{URL: backup.URL},
+	)
+
+	primary.set(http.StatusBadGateway, "")
+	c.CheckEndpoints(context.Background())
+	if got := c.ActiveEndpoint(); got != backup.URL {
+		t.Fatalf("active endpoint = %s, want backup %s after a failed health check", got, backup.URL)
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotModified is returned by GetAdsConditional when the server reports the playlist is unchanged
+var ErrNotModified = errors.New("ads not modified")
+
+// maxErrorBodySize caps how much of an error response body is kept for diagnostics
+const maxErrorBodySize = 4096
+
+// AuthError is returned when the server rejects the screen ID or passkey (401)
+type AuthError struct {
+	Body string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("authentication failed: invalid screen ID or passkey - %s", e.Body)
+}
+
+// ForbiddenError is returned when the screen is authenticated but not allowed to perform the call (403)
+type ForbiddenError struct {
+	Body string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("access forbidden - %s", e.Body)
+}
+
+// NotFoundError is returned when the screen or resource does not exist on the server (404)
+type NotFoundError struct {
+	Resource string
+	Body     string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found - %s", e.Resource, e.Body)
+}
+
+// RateLimitError is returned when the server throttles the screen (429)
+type RateLimitError struct {
+	After time.Duration // Wait requested by the Retry-After header (0 if absent)
+	Body  string
+}
+
+func (e *RateLimitError) Error() string {
+	if e.After > 0 {
+		return fmt.Sprintf("rate limited by server, retry after %v - %s", e.After, e.Body)
+	}
+	return fmt.Sprintf("rate limited by server - %s", e.Body)
+}
+
+// RetryAfter returns the wait requested by the server
+func (e *RateLimitError) RetryAfter() time.Duration {
+	return e.After
+}
+
+// ServerError is returned for 5xx responses
+type ServerError struct {
+	StatusCode int
+	After      time.Duration // Wait requested by the Retry-After header (0 if absent)
+	Body       string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// RetryAfter returns the wait requested by the server
+func (e *ServerError) RetryAfter() time.Duration {
+	return e.After
+}
+
+// StatusError is returned for any other unexpected status code
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// DecodeError is returned when a response body cannot be parsed
+type DecodeError struct {
+	What string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to parse %s: %v", e.What, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseTooLargeError is returned when a response body exceeds the configured size limit
+type ResponseTooLargeError struct {
+	What  string
+	Limit int64 // Maximum decoded size in bytes
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s exceeds the %d byte limit", e.What, e.Limit)
+}
+
+// IsAuthError reports whether err means the screen's credentials were rejected (401 or 403)
+func IsAuthError(err error) bool {
+	var authErr *AuthError
+	var forbiddenErr *ForbiddenError
+	return errors.As(err, &authErr) || errors.As(err, &forbiddenErr)
+}
+
+// IsNotFoundError reports whether err means the screen or resource does not exist
+func IsNotFoundError(err error) bool {
+	var notFoundErr *NotFoundError
+	return errors.As(err, &notFoundErr)
+}
+
+// newStatusError converts a non-success response into a typed error, consuming its body
+func newStatusError(resp *http.Response, resource string) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	body := strings.TrimSpace(string(data))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return &AuthError{Body: body}
+	case resp.StatusCode == http.StatusForbidden:
+		return &ForbiddenError{Body: body}
+	case resp.StatusCode == http.StatusNotFound:
+		return &NotFoundError{Resource: resource, Body: body}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitError{After: parseRetryAfter(resp.Header.Get("Retry-After")), Body: body}
+	case resp.StatusCode >= 500:
+		return &ServerError{
+			StatusCode: resp.StatusCode,
+			After:      parseRetryAfter(resp.Header.Get("Retry-After")),
+			Body:       body,
+		}
+	default:
+		return &StatusError{StatusCode: resp.StatusCode, Body: body}
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(when); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EventType identifies the kind of event pushed by the server
+type EventType string
+
+const (
+	EventPlaylistChanged EventType = "playlist_changed" // New ads are available for delivery
+	EventReload          EventType = "reload"           // Discard cached state and refetch everything
+	EventCommand         EventType = "command"          // Remote command for the screen (see ServerEvent.Command)
+)
+
+// defaultStreamIdleTimeout is how long the event stream may stay silent before it's considered dead
+// Servers are expected to send keep-alive comments well within this window
+const defaultStreamIdleTimeout = 90 * time.Second
+
+// ServerEvent is a single event received on the push channel
+type ServerEvent struct {
+	ID   string          // Event ID (used to resume with Last-Event-ID)
+	Type EventType       // Event type
+	Data json.RawMessage // Raw event payload
+}
+
+// CommandPayload is the payload of an EventCommand event
+type CommandPayload struct {
+	Command string                 `json:"command"`
+	Args    map[string]interface{} `json:"args,omitempty"`
+}
+
+// Command decodes the payload of a command event
+func (e ServerEvent) Command() (*CommandPayload, error) {
+	if e.Type != EventCommand {
+		return nil, fmt.Errorf("event %s is not a command", e.Type)
+	}
+	var cmd CommandPayload
+	if err := json.Unmarshal(e.Data, &cmd); err != nil {
+		return nil, fmt.Errorf("failed to parse command payload: %w", err)
+	}
+	return &cmd, nil
+}
+
+// StreamEvents opens the Server-Sent Events channel for the screen and calls handler for each event
+// onConnected, if set, is called once the server accepts the stream.
+// It blocks until ctx is cancelled or the stream drops, and always returns a non-nil error.
+func (c *Client) StreamEvents(ctx context.Context, screenID string, lastEventID string, onConnected func(), handler func(ServerEvent)) error {
+	url := fmt.Sprintf("%s/api/v1/screens/%s/events", c.endpoints.activeURL(), screenID)
+
+	// The idle watchdog cancels the stream if nothing arrives for too long
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The stream bypasses doRequest, so refresh an expiring access token here
+	c.ensureSession(streamCtx)
+	req, err := c.createRequest(streamCtx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	// Streams are long-lived, so don't apply the request timeout of the API client
+	streamClient := &http.Client{Transport: c.httpClient.Transport}
+
+	log.Printf("[%s] [PUSH] Opening event stream: %s", time.Now().Format("15:04:05.000"), url)
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to open event stream: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("event stream rejected: %w", newStatusError(resp, "event stream"))
+	}
+
+	log.Printf("[%s] [PUSH] Event stream connected", time.Now().Format("15:04:05.000"))
+	if onConnected != nil {
+		onConnected()
+	}
+
+	idleTimeout := c.streamIdleTimeout
+	idle := time.AfterFunc(idleTimeout, cancel)
+	defer idle.Stop()
+
+	var event ServerEvent
+	var data []string
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		idle.Reset(idleTimeout)
+		line := scanner.Text()
+
+		// A blank line dispatches the accumulated event
+		if line == "" {
+			if len(data) > 0 || event.Type != "" {
+				if event.Type == "" {
+					event.Type = "message"
+				}
+				event.Data = json.RawMessage(strings.Join(data, "\n"))
+				handler(event)
+			}
+			event = ServerEvent{ID: event.ID}
+			data = data[:0]
+			continue
+		}
+
+		// Lines starting with a colon are comments (keep-alives)
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Type = EventType(value)
+		case "data":
+			data = append(data, value)
+		case "id":
+			event.ID = value
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if streamCtx.Err() != nil {
+		return fmt.Errorf("event stream idle for more than %v", idleTimeout)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("event stream interrupted: %w", err)
+	}
+	return fmt.Errorf("event stream closed by server")
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamEventsParsesEventsAndResumes(t *testing.T) {
+	var lastEventID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/screens/screen-1/events" {
+			http.NotFound(w, r)
+			return
+		}
+		lastEventID = r.Header.Get("Last-Event-ID")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "id: 42\nevent: playlist_changed\ndata: {}\n\n")
+		fmt.Fprint(w, "event: command\ndata: {\"command\":\n")
+		fmt.Fprint(w, "data: \"pause\"}\n\n")
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "screen-1", "passkey")
+	connected := false
+	var events []ServerEvent
+	err := c.StreamEvents(context.Background(), "screen-1", "41", func() { connected = true }, func(event ServerEvent) {
+		events = append(events, event)
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "closed by server") {
+		t.Errorf("err = %v, want the stream closed by the server", err)
+	}
+	if lastEventID != "41" {
+		t.Errorf("Last-Event-ID = %q, want 41", lastEventID)
+	}
+	if !connected {
+		t.Error("onConnected was not called")
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	if events[0].Type != EventPlaylistChanged || events[0].ID != "42" {
+		t.Errorf("first event = %+v, want playlist_changed with id 42", events[0])
+	}
+	// Events without an id keep the last one, so a reconnect resumes after it
+	if events[1].Type != EventCommand || events[1].ID != "42" {
+		t.Errorf("second event = %+v, want a command carrying id 42", events[1])
+	}
+	cmd, err := events[1].Command()
+	if err != nil || cmd.Command != "pause" {
+		t.Errorf("command = %+v (%v), want pause from the multi-line data", cmd, err)
+	}
+}
+
+func TestStreamEventsIdleWatchdog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "screen-1", "passkey")
+	c.streamIdleTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := c.StreamEvents(context.Background(), "screen-1", "", nil, func(ServerEvent) {})
+	if err == nil || !strings.Contains(err.Error(), "idle") {
+		t.Fatalf("err = %v, want an idle timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("watchdog took %v", elapsed)
+	}
+}
+
+func TestStreamEventsRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "screen-1", "passkey")
+	err := c.StreamEvents(context.Background(), "screen-1", "", func() {
+		t.Error("onConnected called for a rejected stream")
+	}, func(ServerEvent) {})
+	if !IsAuthError(err) {
+		t.Errorf("err = %v, want an auth error", err)
+	}
+}
+
+func TestStreamEventsRefreshesSession(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/screens/screen-1/token/refresh":
+			fmt.Fprint(w, `{"accessToken":"fresh","expiresIn":3600}`)
+		case "/api/v1/screens/screen-1/events":
+			authorization = r.Header.Get("Authorization")
+			w.Header().Set("Content-Type", "text/event-stream")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "screen-1", "passkey")
+	c.SetRefreshToken("refresh")
+	c.StreamEvents(context.Background(), "screen-1", "", nil, func(ServerEvent) {})
+
+	if authorization != "Bearer fresh" {
+		t.Errorf("Authorization = %q, want the refreshed access token", authorization)
+	}
+}
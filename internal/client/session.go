@@ -0,0 +1,221 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mnemoCast-client/internal/models"
+	"mnemoCast-client/pkg/signing"
+	"net/http"
+	"time"
+)
+
+// sessionRefreshMargin is how long before expiry the access token is refreshed
+const sessionRefreshMargin = 60 * time.Second
+
+// authHeaders lists every header authorize may set, so it can switch schemes between attempts
+var authHeaders = []string{
+	"Authorization",
+	"X-Screen-Passkey",
+	signing.HeaderVersion,
+	signing.HeaderTimestamp,
+	signing.HeaderNonce,
+	signing.HeaderContentSHA256,
+	signing.HeaderSignature,
+}
+
+type passkeyAuthKey struct{}
+
+// withPasskeyAuth marks requests that must authenticate with the passkey even when a session exists
+func withPasskeyAuth(ctx context.Context) context.Context {
+	return context.WithValue(ctx, passkeyAuthKey{}, true)
+}
+
+// passkeyOnly reports whether ctx was marked with withPasskeyAuth
+func passkeyOnly(ctx context.Context) bool {
+	only, _ := ctx.Value(passkeyAuthKey{}).(bool)
+	return only
+}
+
+// tokenResponse is the token part of connect and refresh responses
+type tokenResponse struct {
+	AccessToken  string    `json:"accessToken,omitempty"`
+	ExpiresIn    int       `json:"expiresIn,omitempty"` // Seconds until the access token expires
+	ExpiresAt    time.Time `json:"expiresAt,omitempty"` // Absolute expiry (takes precedence over expiresIn)
+	RefreshToken string    `json:"refreshToken,omitempty"`
+}
+
+// toSession converts the response into a session token
+func (t tokenResponse) toSession(now time.Time) *models.SessionToken {
+	session := &models.SessionToken{
+		AccessToken:  t.AccessToken,
+		ExpiresAt:    t.ExpiresAt,
+		RefreshToken: t.RefreshToken,
+	}
+	if session.ExpiresAt.IsZero() && t.ExpiresIn > 0 {
+		session.ExpiresAt = now.Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return session
+}
+
+// SetOnSessionUpdated sets a callback invoked with the current refresh token whenever it changes
+// An empty token means the session was revoked and any persisted token should be discarded
+func (c *Client) SetOnSessionUpdated(callback func(refreshToken string)) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.onSessionUpdated = callback
+}
+
+// SetRefreshToken restores a refresh token persisted by a previous run
+// The access token is obtained on the next request
+func (c *Client) SetRefreshToken(refreshToken string) {
+	if refreshToken == "" {
+		return
+	}
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	if c.session == nil {
+		c.session = &models.SessionToken{}
+	}
+	c.session.RefreshToken = refreshToken
+}
+
+// HasSession reports whether the client currently holds a usable access token
+func (c *Client) HasSession() bool {
+	return c.accessToken() != ""
+}
+
+// accessToken returns the current access token if it hasn't expired
+func (c *Client) accessToken() string {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	if c.session == nil || !c.session.IsValid(time.Now(), 0) {
+		return ""
+	}
+	return c.session.AccessToken
+}
+
+// sessionExpiry returns the expiry of the current access token
+func (c *Client) sessionExpiry() time.Time {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	if c.session == nil {
+		return time.Time{}
+	}
+	return c.session.ExpiresAt
+}
+
+// setSession replaces the session, keeping the old refresh token if the server didn't rotate it
+func (c *Client) setSession(session *models.SessionToken) {
+	c.sessionMu.Lock()
+	if session != nil && session.RefreshToken == "" && c.session != nil {
+		session.RefreshToken = c.session.RefreshToken
+	}
+	previous := ""
+	if c.session != nil {
+		previous = c.session.RefreshToken
+	}
+	c.session = session
+	current := ""
+	if session != nil {
+		current = session.RefreshToken
+	}
+	callback := c.onSessionUpdated
+	c.sessionMu.Unlock()
+
+	if callback != nil && current != previous {
+		callback(current)
+	}
+}
+
+// ensureSession refreshes the access token if it is missing or about to expire
+func (c *Client) ensureSession(ctx context.Context) {
+	c.sessionMu.RLock()
+	session := c.session
+	c.sessionMu.RUnlock()
+
+	if session == nil || session.RefreshToken == "" || session.IsValid(time.Now(), sessionRefreshMargin) {
+		return
+	}
+	c.refreshSession(ctx, false)
+}
+
+// refreshSession exchanges the refresh token for a new access token
+// force refreshes even if the current token looks valid (e.g. after a 401).
+// On failure the access token is dropped so requests fall back to the passkey.
+func (c *Client) refreshSession(ctx context.Context, force bool) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.sessionMu.RLock()
+	session := c.session
+	c.sessionMu.RUnlock()
+
+	// Another goroutine may have refreshed while we waited
+	if session == nil {
+		return
+	}
+	if !force && session.IsValid(time.Now(), sessionRefreshMargin) {
+		return
+	}
+	if session.RefreshToken == "" {
+		c.setSession(nil)
+		return
+	}
+
+	refreshed, err := c.requestTokenRefresh(ctx, session.RefreshToken)
+	if err != nil {
+		log.Printf("[%s] [AUTH] [WARN] Session refresh failed, falling back to passkey: %v",
+			time.Now().Format("15:04:05.000"), err)
+		if IsAuthError(err) {
+			// The refresh token itself was revoked
+			c.setSession(nil)
+		} else {
+			c.setSession(&models.SessionToken{RefreshToken: session.RefreshToken})
+		}
+		return
+	}
+
+	c.setSession(refreshed)
+	log.Printf("[%s] [AUTH] Session refreshed (expires: %s)",
+		time.Now().Format("15:04:05.000"), refreshed.ExpiresAt.Format(time.RFC3339))
+}
+
+// requestTokenRefresh calls the token refresh endpoint once, without retries or session headers
+func (c *Client) requestTokenRefresh(ctx context.Context, refreshToken string) (*models.SessionToken, error) {
+	url := fmt.Sprintf("%s/api/v1/screens/%s/token/refresh", c.endpoints.activeURL(), c.screenID)
+
+	body, err := json.Marshal(map[string]string{"refreshToken": refreshToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal refresh request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Screen-Id", c.screenID)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("refresh request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(resp, "session")
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, &DecodeError{What: "token refresh response", Err: err}
+	}
+	if token.AccessToken == "" {
+		return nil, &DecodeError{What: "token refresh response", Err: fmt.Errorf("missing accessToken")}
+	}
+
+	return token.toSession(time.Now()), nil
+}
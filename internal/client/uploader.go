@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mnemoCast-client/internal/models"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EventQueue is the durable queue the uploader drains
+type EventQueue interface {
+	// Peek returns up to n of the oldest records without removing them
+	Peek(n int) []models.PlayRecord
+	// Ack removes acknowledged records from the queue
+	Ack(ids []string) error
+	// Len returns the number of queued records
+	Len() int
+}
+
+// UploadProofOfPlay sends a batch of proof-of-play records and returns the server acknowledgement
+func (c *Client) UploadProofOfPlay(ctx context.Context, screenID string, records []models.PlayRecord) (*models.ProofOfPlayAck, error) {
+	url := fmt.Sprintf("%s/api/v1/screens/%s/proof-of-play", c.endpoints.activeURL(), screenID)
+
+	req, err := c.createRequest(ctx, "POST", url, models.ProofOfPlayUploadRequest{Records: records})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("proof-of-play upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("proof-of-play upload failed: %w", newStatusError(resp, "screen "+screenID))
+	}
+
+	var ack models.ProofOfPlayAck
+	if err := json.NewDecoder(resp.Body).Decode(&ack); err != nil {
+		return nil, &DecodeError{What: "proof-of-play acknowledgement", Err: err}
+	}
+
+	return &ack, nil
+}
+
+// Uploader periodically drains the proof-of-play queue to the server in batches
+// Records stay queued until the server acknowledges them, so nothing is lost while offline
+type Uploader struct {
+	client    *Client
+	screenID  string
+	queue     EventQueue
+	interval  time.Duration
+	batchSize int
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	mu         sync.RWMutex
+	uploaded   int
+	rejected   int
+	lastUpload time.Time
+	lastError  error
+}
+
+// NewUploader creates a new proof-of-play uploader
+func NewUploader(adClient *Client, screenID string, queue EventQueue, intervalSeconds int, batchSize int) *Uploader {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if intervalSeconds <= 0 {
+		intervalSeconds = 60 // time.NewTicker panics on a non-positive interval
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	return &Uploader{
+		client:    adClient,
+		screenID:  screenID,
+		queue:     queue,
+		interval:  time.Duration(intervalSeconds) * time.Second,
+		batchSize: batchSize,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start starts the uploader in a background goroutine
+func (u *Uploader) Start() {
+	u.wg.Add(1)
+	go u.run()
+	log.Printf("[UPLOAD] Proof-of-play uploader started (interval: %v, batch size: %d)", u.interval, u.batchSize)
+}
+
+// Stop stops the uploader; queued records remain on disk for the next run
+func (u *Uploader) Stop() {
+	log.Println("[UPLOAD] Stopping proof-of-play uploader...")
+	u.cancel()
+	u.wg.Wait()
+	log.Println("[UPLOAD] Proof-of-play uploader stopped")
+}
+
+// run is the main uploader loop
+func (u *Uploader) run() {
+	defer u.wg.Done()
+
+	// Flush anything left over from previous runs right away
+	u.flush()
+
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-u.ctx.Done():
+			return
+		case <-ticker.C:
+			u.flush()
+		}
+	}
+}
+
+// flush uploads batches until the queue is empty or an upload fails
+func (u *Uploader) flush() {
+	for u.ctx.Err() == nil {
+		batch := u.queue.Peek(u.batchSize)
+		if len(batch) == 0 {
+			return
+		}
+
+		ack, err := u.client.UploadProofOfPlay(u.ctx, u.screenID, batch)
+		if err != nil {
+			if u.ctx.Err() != nil {
+				return
+			}
+			u.mu.Lock()
+			u.lastError = err
+			u.mu.Unlock()
+			log.Printf("[%s] [UPLOAD] [WARN] Proof-of-play upload failed, %d records kept queued: %v",
+				time.Now().Format("15:04:05.000"), u.queue.Len(), err)
+			return
+		}
+
+		done := append(append([]string{}, ack.Accepted...), ack.Rejected...)
+		if err := u.queue.Ack(done); err != nil {
+			log.Printf("[%s] [UPLOAD] [WARN] Failed to remove acknowledged records: %v",
+				time.Now().Format("15:04:05.000"), err)
+		}
+
+		u.mu.Lock()
+		u.uploaded += len(ack.Accepted)
+		u.rejected += len(ack.Rejected)
+		u.lastUpload = time.Now()
+		u.lastError = nil
+		u.mu.Unlock()
+
+		if len(ack.Rejected) > 0 {
+			log.Printf("[%s] [UPLOAD] [WARN] Server rejected %d proof-of-play records",
+				time.Now().Format("15:04:05.000"), len(ack.Rejected))
+		}
+		log.Printf("[%s] [UPLOAD] Uploaded %d proof-of-play records (%d pending)",
+			time.Now().Format("15:04:05.000"), len(ack.Accepted), u.queue.Len())
+
+		// Avoid spinning if the server acknowledged nothing
+		if len(done) == 0 {
+			return
+		}
+	}
+}
+
+// GetStats returns uploader statistics
+func (u *Uploader) GetStats() map[string]interface{} {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	stats := map[string]interface{}{
+		"pending":    u.queue.Len(),
+		"uploaded":   u.uploaded,
+		"rejected":   u.rejected,
+		"lastUpload": u.lastUpload,
+	}
+
+	if u.lastError != nil {
+		stats["lastError"] = u.lastError.Error()
+	}
+
+	return stats
+}
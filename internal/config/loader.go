@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"mnemoCast-client/internal/models"
+	"mnemoCast-client/pkg/storage"
+	"net/url"
 	"os"
 	"path/filepath"
 )
@@ -25,19 +27,25 @@ func NewLoader(configDir string) *Loader {
 // Load loads the configuration from file
 func (l *Loader) Load() (*models.ScreenConfig, error) {
 	// Check if config file exists
-	if _, err := os.Stat(l.configFile); os.IsNotExist(err) {
+	if !storage.ExistsWithBackup(l.configFile) {
 		// Return default config if file doesn't exist
 		return l.CreateDefault()
 	}
 
-	data, err := os.ReadFile(l.configFile)
+	var config models.ScreenConfig
+	restored, err := storage.ReadFileWithBackup(l.configFile, func(data []byte) error {
+		var parsed models.ScreenConfig
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return fmt.Errorf("failed to parse config: %w", err)
+		}
+		config = parsed
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
-
-	var config models.ScreenConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	if restored {
+		fmt.Printf("Warning: %s was unreadable, restored the last-known-good copy\n", l.configFile)
 	}
 
 	// Apply defaults for missing fields
@@ -50,6 +58,22 @@ func (l *Loader) Load() (*models.ScreenConfig, error) {
 		config.HeartbeatInterval = 30 // Default: heartbeat every 30 seconds
 		needsSave = true
 	}
+	if config.MinPollInterval == 0 {
+		config.MinPollInterval = 10 // Default: the server can't ask for polling more often than every 10 seconds
+		needsSave = true
+	}
+	if config.MaxPollInterval == 0 {
+		config.MaxPollInterval = 3600 // Default: nor less often than hourly
+		needsSave = true
+	}
+	if config.EndpointHealthInterval == 0 {
+		config.EndpointHealthInterval = 30 // Default: health check the ad server endpoints every 30 seconds; -1 disables them
+		needsSave = true
+	}
+	if config.EndpointFailBackDelay == 0 {
+		config.EndpointFailBackDelay = 120 // Default: fail back once the preferred endpoint has been healthy for 2 minutes; -1 fails back at once
+		needsSave = true
+	}
 	if config.RetryAttempts == 0 {
 		config.RetryAttempts = 3 // Default: 3 retry attempts
 		needsSave = true
@@ -58,6 +82,70 @@ func (l *Loader) Load() (*models.ScreenConfig, error) {
 		config.RetryDelay = 5 // Default: 5 seconds between retries
 		needsSave = true
 	}
+	if config.RetryMaxDelay == 0 {
+		config.RetryMaxDelay = 60 // Default: cap retry backoff at 60 seconds
+		needsSave = true
+	}
+	if config.ProofOfPlayUploadInterval == 0 {
+		config.ProofOfPlayUploadInterval = 60 // Default: upload proof of play every minute
+		needsSave = true
+	}
+	if config.ProofOfPlayBatchSize == 0 {
+		config.ProofOfPlayBatchSize = 100 // Default: 100 records per upload
+		needsSave = true
+	}
+	if config.EventQueueMaxSize == 0 {
+		config.EventQueueMaxSize = 50000 // Default: roughly two weeks offline at one ad every 30 seconds
+		needsSave = true
+	}
+	if config.MaxAdResponseSize == 0 {
+		config.MaxAdResponseSize = 10 << 20 // Default: 10 MiB decoded playlist
+		needsSave = true
+	}
+	if config.PlaylistHistorySize == 0 {
+		config.PlaylistHistorySize = 10 // Default: keep the last 10 playlists for rollback; -1 disables history
+		needsSave = true
+	}
+	if config.PrefetchConcurrency > 0 {
+		// Configs from before the download pool set prefetchConcurrency instead of downloadWorkers
+		if config.DownloadWorkers == 0 {
+			config.DownloadWorkers = config.PrefetchConcurrency
+		}
+		config.PrefetchConcurrency = 0
+		needsSave = true
+	}
+	if config.DownloadWorkers == 0 {
+		config.DownloadWorkers = 3 // Default: 3 parallel media downloads
+		needsSave = true
+	}
+	if config.DownloadsPerHost == 0 {
+		config.DownloadsPerHost = 2 // Default: at most 2 connections per media host
+		needsSave = true
+	}
+	if config.PrefetchReadyFraction == 0 {
+		config.PrefetchReadyFraction = 1.0 // Default: wait for every ad's media
+		needsSave = true
+	}
+	if config.PrefetchTimeout == 0 {
+		config.PrefetchTimeout = 600 // Default: switch after 10 minutes even if media is missing
+		needsSave = true
+	}
+	if config.DownloadStallTimeout == 0 {
+		config.DownloadStallTimeout = 30 // Default: retry a download after 30 seconds without data
+		needsSave = true
+	}
+	if config.MediaCacheQuota == 0 {
+		config.MediaCacheQuota = 2 << 30 // Default: 2 GiB of cached media
+		needsSave = true
+	}
+	if config.MinFreeDiskSpace == 0 {
+		config.MinFreeDiskSpace = 256 << 20 // Default: keep 256 MiB free on disk
+		needsSave = true
+	}
+
+	if err := validateEndpoints(config.AdServerURLs); err != nil {
+		return nil, err
+	}
 
 	// Save updated config if we added defaults (to persist new fields)
 	if needsSave {
@@ -70,6 +158,17 @@ func (l *Loader) Load() (*models.ScreenConfig, error) {
 	return &config, nil
 }
 
+// validateEndpoints checks that every configured ad server endpoint is an absolute http(s) URL
+func validateEndpoints(endpoints []models.ServerEndpoint) error {
+	for i, endpoint := range endpoints {
+		parsed, err := url.Parse(endpoint.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("adServerUrls entry %d: %q is not an http(s) URL", i+1, endpoint.URL)
+		}
+	}
+	return nil
+}
+
 // Save saves the configuration to file
 func (l *Loader) Save(config *models.ScreenConfig) error {
 	// Ensure config directory exists
@@ -82,8 +181,12 @@ func (l *Loader) Save(config *models.ScreenConfig) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	// Write with restricted permissions
-	if err := os.WriteFile(l.configFile, data, 0600); err != nil {
+	// Replace atomically, keeping the previous config as the last-known-good backup if it still parses
+	validate := func(current []byte) error {
+		var parsed models.ScreenConfig
+		return json.Unmarshal(current, &parsed)
+	}
+	if err := storage.WriteFileWithBackup(l.configFile, data, 0600, validate); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
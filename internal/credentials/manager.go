@@ -64,11 +64,15 @@ func (m *Manager) Save(creds *models.Credentials) error {
 }
 
 // SetCredentials sets both screen ID and passkey in credentials
+// A stored refresh token is kept while the screen ID stays the same, since it was issued to that screen
 func (m *Manager) SetCredentials(screenID string, passkey string) error {
 	creds := &models.Credentials{
 		ScreenID: screenID,
 		Passkey:  passkey,
 	}
+	if existing, err := m.Load(); err == nil && existing.ScreenID == screenID {
+		creds.RefreshToken = existing.RefreshToken
+	}
 
 	return m.Save(creds)
 }
@@ -87,6 +91,27 @@ func (m *Manager) GetCredentials() (screenID string, passkey string, err error)
 	return creds.ScreenID, creds.Passkey, nil
 }
 
+// SetRefreshToken stores the session refresh token alongside the credentials
+// An empty token removes it
+func (m *Manager) SetRefreshToken(refreshToken string) error {
+	creds, err := m.Load()
+	if err != nil {
+		return err
+	}
+
+	creds.RefreshToken = refreshToken
+	return m.Save(creds)
+}
+
+// GetRefreshToken returns the stored session refresh token, or "" if none
+func (m *Manager) GetRefreshToken() string {
+	creds, err := m.Load()
+	if err != nil {
+		return ""
+	}
+	return creds.RefreshToken
+}
+
 // Validate checks if credentials are valid
 func (m *Manager) Validate() error {
 	creds, err := m.Load()
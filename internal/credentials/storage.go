@@ -40,7 +40,7 @@ func (s *Storage) getOrCreateKey() ([]byte, error) {
 	}
 
 	// Save key with restricted permissions
-	if err := os.WriteFile(s.keyFile, key, 0600); err != nil {
+	if err := storage.WriteFileAtomic(s.keyFile, key, 0600); err != nil {
 		return nil, fmt.Errorf("failed to save encryption key: %w", err)
 	}
 
@@ -72,8 +72,12 @@ func (s *Storage) Save(creds *models.Credentials) error {
 		return fmt.Errorf("failed to encrypt credentials: %w", err)
 	}
 
-	// Save encrypted credentials
-	if err := os.WriteFile(s.credsFile, []byte(encryptedBase64), 0600); err != nil {
+	// Save encrypted credentials atomically, keeping the previous copy as a backup if it still decrypts
+	validate := func(current []byte) error {
+		_, err := decodeCredentials(current, key)
+		return err
+	}
+	if err := storage.WriteFileWithBackup(s.credsFile, []byte(encryptedBase64), 0600, validate); err != nil {
 		return fmt.Errorf("failed to save credentials: %w", err)
 	}
 
@@ -83,7 +87,7 @@ func (s *Storage) Save(creds *models.Credentials) error {
 // Load loads credentials from secure storage
 func (s *Storage) Load() (*models.Credentials, error) {
 	// Check if credentials file exists
-	if _, err := os.Stat(s.credsFile); os.IsNotExist(err) {
+	if !s.Exists() {
 		return nil, fmt.Errorf("credentials file not found")
 	}
 
@@ -93,36 +97,45 @@ func (s *Storage) Load() (*models.Credentials, error) {
 		return nil, err
 	}
 
-	// Read encrypted credentials
-	encryptedBase64, err := os.ReadFile(s.credsFile)
+	// Read, decrypt and unmarshal, falling back to the backup if the file is damaged
+	var creds models.Credentials
+	_, err = storage.ReadFileWithBackup(s.credsFile, func(encryptedBase64 []byte) error {
+		parsed, err := decodeCredentials(encryptedBase64, key)
+		if err != nil {
+			return err
+		}
+		creds = *parsed
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+		return nil, err
 	}
 
-	// Decrypt credentials
+	return &creds, nil
+}
+
+// decodeCredentials decrypts and unmarshals the stored form of the credentials
+func decodeCredentials(encryptedBase64 []byte, key []byte) (*models.Credentials, error) {
 	data, err := storage.DecryptFromBase64(string(encryptedBase64), key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt credentials: %w", err)
 	}
 
-	// Unmarshal credentials
 	var creds models.Credentials
 	if err := json.Unmarshal(data, &creds); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal credentials: %w", err)
 	}
-
 	return &creds, nil
 }
 
-// Exists checks if credentials file exists
+// Exists checks if credentials file (or its backup) exists
 func (s *Storage) Exists() bool {
-	_, err := os.Stat(s.credsFile)
-	return err == nil
+	return storage.ExistsWithBackup(s.credsFile)
 }
 
 // Delete removes credentials file (use with caution)
 func (s *Storage) Delete() error {
-	if err := os.Remove(s.credsFile); err != nil && !os.IsNotExist(err) {
+	if err := storage.RemoveWithBackup(s.credsFile); err != nil {
 		return fmt.Errorf("failed to delete credentials: %w", err)
 	}
 	return nil
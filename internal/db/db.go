@@ -0,0 +1,146 @@
+package db
+
+import (
+	"fmt"
+	"mnemoCast-client/pkg/storage"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the database file inside the config directory
+const FileName = "mnemocast.db"
+
+// ErrInUse is returned by Open while another process, normally the screen, holds the database
+var ErrInUse = storage.ErrInUse
+
+// SnapshotFileName is the read-only copy of the database the screen exports while it holds the lock
+const SnapshotFileName = "mnemocast.snapshot.db"
+
+// Buckets of the screen database
+const (
+	BucketPlaylists       = "playlists"        // Current playlist and its cache validators
+	BucketPlaylistHistory = "playlist_history" // Playlist snapshots for rollback, keyed by save time
+	BucketIdentity        = "identity"         // Screen identity
+	BucketMediaIndex      = "media_index"      // Downloaded media metadata
+	BucketEvents          = "events"           // Proof-of-play records awaiting upload, keyed by sequence
+	BucketHeartbeats      = "heartbeats"       // Recent heartbeat results, keyed by time
+)
+
+// migrations is the schema history of the screen database; append only
+var migrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create buckets",
+		Apply: func(tx storage.Tx) error {
+			for _, bucket := range []string{
+				BucketPlaylists,
+				BucketPlaylistHistory,
+				BucketIdentity,
+				BucketMediaIndex,
+				BucketEvents,
+				BucketHeartbeats,
+			} {
+				if err := tx.CreateBucket(bucket); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+var (
+	mu     sync.Mutex
+	stores = map[string]storage.Store{}
+)
+
+// Open returns the screen database in configDir, migrating it to the current schema
+// Components opened with the same directory share one store
+func Open(configDir string) (storage.Store, error) {
+	path := filepath.Join(configDir, FileName)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if store, ok := stores[path]; ok {
+		return store, nil
+	}
+
+	store, err := storage.OpenBolt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storage.Migrate(store, migrations); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	stores[path] = store
+	return store, nil
+}
+
+// ExportSnapshot writes a copy of the open database in configDir for other processes to read
+// See OpenSnapshot
+func ExportSnapshot(configDir string) error {
+	store, err := Open(configDir)
+	if err != nil {
+		return err
+	}
+	boltStore, ok := store.(*storage.BoltStore)
+	if !ok {
+		return fmt.Errorf("database does not support snapshots")
+	}
+	return boltStore.WriteSnapshot(filepath.Join(configDir, SnapshotFileName))
+}
+
+// OpenSnapshot opens the snapshot exported by the process holding the database, read-only,
+// and returns when it was written. Until CloseAll, Open with the same directory returns the
+// snapshot, so components read it like the live database; their writes fail.
+func OpenSnapshot(configDir string) (time.Time, error) {
+	snapshotPath := filepath.Join(configDir, SnapshotFileName)
+	info, err := os.Stat(snapshotPath)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("no database snapshot: %w", err)
+	}
+
+	path := filepath.Join(configDir, FileName)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := stores[path]; ok {
+		return time.Time{}, fmt.Errorf("database %s is already open", FileName)
+	}
+
+	store, err := storage.OpenBoltReadOnly(snapshotPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	stores[path] = store
+	return info.ModTime(), nil
+}
+
+// CloseAll closes every open screen database; call it on shutdown once nothing uses them
+func CloseAll() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for path, store := range stores {
+		store.Close()
+		delete(stores, path)
+	}
+}
+
+// SchemaVersion returns the schema version this build migrates to
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
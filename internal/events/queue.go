@@ -0,0 +1,287 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mnemoCast-client/internal/db"
+	"mnemoCast-client/internal/models"
+	"mnemoCast-client/pkg/storage"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Queue is a durable queue of proof-of-play records
+// Records are kept in the screen database, so they survive restarts and long
+// offline periods until the server acknowledges them
+type Queue struct {
+	store     storage.Store
+	legacyFile string // JSON-lines queue written by older versions, imported once
+	maxSize   int
+
+	mu      sync.Mutex
+	records []models.PlayRecord
+	keys    []string // Database key of each record, parallel to records
+	nextSeq uint64
+	dropped int
+}
+
+// NewQueue opens the queue in the config directory's database, loading records left from previous runs
+func NewQueue(configDir string, maxSize int) (*Queue, error) {
+	store, err := db.Open(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event queue: %w", err)
+	}
+
+	q := &Queue{
+		store:      store,
+		legacyFile: filepath.Join(configDir, "events", "proof_of_play.jsonl"),
+		maxSize:    maxSize,
+	}
+
+	if err := q.importLegacy(); err != nil {
+		return nil, err
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// load reads the queued records and deletes any that can't be parsed
+// A corrupt record can never be uploaded, so keeping it would only repeat the warning on every start
+func (q *Queue) load() error {
+	var corrupt []string
+	err := q.store.Update(func(tx storage.Tx) error {
+		err := tx.ForEach(db.BucketEvents, func(key string, value []byte) error {
+			var seq uint64
+			fmt.Sscanf(key, "%d", &seq)
+			if seq >= q.nextSeq {
+				q.nextSeq = seq + 1
+			}
+
+			var record models.PlayRecord
+			if err := json.Unmarshal(value, &record); err != nil || record.ID == "" {
+				corrupt = append(corrupt, key)
+				return nil
+			}
+			q.records = append(q.records, record)
+			q.keys = append(q.keys, key)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Keys can't be deleted while the bucket is being walked
+		for _, key := range corrupt {
+			if err := tx.Delete(db.BucketEvents, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read event queue: %w", err)
+	}
+
+	if len(corrupt) > 0 {
+		log.Printf("[%s] [EVENTS] [WARN] Removed %d corrupt records from the event queue",
+			time.Now().Format("15:04:05.000"), len(corrupt))
+	}
+
+	return nil
+}
+
+// importLegacy moves records from the JSON-lines queue file into the database and removes the file
+func (q *Queue) importLegacy() error {
+	data, err := os.ReadFile(q.legacyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read legacy event queue: %w", err)
+	}
+
+	var records [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record models.PlayRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.ID == "" {
+			continue
+		}
+		records = append(records, append([]byte(nil), scanner.Bytes()...))
+	}
+
+	err = q.store.Update(func(tx storage.Tx) error {
+		// Append after anything already queued
+		var last uint64
+		if err := tx.ForEach(db.BucketEvents, func(key string, _ []byte) error {
+			fmt.Sscanf(key, "%d", &last)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for i, record := range records {
+			if err := tx.Put(db.BucketEvents, storage.SequenceKey(last+uint64(i)+1), record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import legacy event queue: %w", err)
+	}
+
+	if err := os.Remove(q.legacyFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove legacy event queue: %w", err)
+	}
+
+	log.Printf("[%s] [EVENTS] Imported %d queued records from %s",
+		time.Now().Format("15:04:05.000"), len(records), q.legacyFile)
+	return nil
+}
+
+// Enqueue durably appends a record, assigning an ID and queue time if missing
+// When the queue is full the oldest record is dropped to make room
+func (q *Queue) Enqueue(record models.PlayRecord) error {
+	if record.ID == "" {
+		id, err := newRecordID()
+		if err != nil {
+			return err
+		}
+		record.ID = id
+	}
+	if record.QueuedAt.IsZero() {
+		record.QueuedAt = time.Now()
+	}
+
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal play record: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	overflow := 0
+	if q.maxSize > 0 && len(q.records) >= q.maxSize {
+		overflow = len(q.records) - q.maxSize + 1
+	}
+
+	key := storage.SequenceKey(q.nextSeq)
+	err = q.store.Update(func(tx storage.Tx) error {
+		for _, oldKey := range q.keys[:overflow] {
+			if err := tx.Delete(db.BucketEvents, oldKey); err != nil {
+				return err
+			}
+		}
+		return tx.Put(db.BucketEvents, key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append to event queue: %w", err)
+	}
+
+	if overflow > 0 {
+		q.records = append([]models.PlayRecord(nil), q.records[overflow:]...)
+		q.keys = append([]string(nil), q.keys[overflow:]...)
+		q.dropped += overflow
+		log.Printf("[%s] [EVENTS] [WARN] Event queue full (%d records), dropped %d oldest",
+			time.Now().Format("15:04:05.000"), q.maxSize, overflow)
+	}
+
+	q.nextSeq++
+	q.records = append(q.records, record)
+	q.keys = append(q.keys, key)
+	return nil
+}
+
+// Peek returns up to n of the oldest records without removing them
+func (q *Queue) Peek(n int) []models.PlayRecord {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n <= 0 || n > len(q.records) {
+		n = len(q.records)
+	}
+	batch := make([]models.PlayRecord, n)
+	copy(batch, q.records[:n])
+	return batch
+}
+
+// Ack removes the records with the given IDs once the server has acknowledged them
+func (q *Queue) Ack(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	acked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		acked[id] = true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var remaining []models.PlayRecord
+	var remainingKeys, ackedKeys []string
+	for i, record := range q.records {
+		if acked[record.ID] {
+			ackedKeys = append(ackedKeys, q.keys[i])
+		} else {
+			remaining = append(remaining, record)
+			remainingKeys = append(remainingKeys, q.keys[i])
+		}
+	}
+	if len(ackedKeys) == 0 {
+		return nil
+	}
+
+	err := q.store.Update(func(tx storage.Tx) error {
+		for _, key := range ackedKeys {
+			if err := tx.Delete(db.BucketEvents, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove acknowledged records: %w", err)
+	}
+
+	q.records = remaining
+	q.keys = remainingKeys
+	return nil
+}
+
+// Len returns the number of records waiting for upload
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.records)
+}
+
+// Dropped returns the number of records discarded because the queue was full
+func (q *Queue) Dropped() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// newRecordID generates a random record ID
+func newRecordID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate record ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,107 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mnemoCast-client/internal/db"
+	"mnemoCast-client/pkg/storage"
+	"time"
+)
+
+// DefaultHistorySize is the number of heartbeat results kept
+const DefaultHistorySize = 1000
+
+// Record is the stored result of one heartbeat cycle
+type Record struct {
+	At         time.Time `json:"at"`
+	Status     string    `json:"status"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// History keeps recent heartbeat results in the screen database for diagnostics
+type History struct {
+	store storage.Store
+	limit int
+}
+
+// NewHistory opens the heartbeat history in the config directory's database
+func NewHistory(configDir string, limit int) (*History, error) {
+	store, err := db.Open(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open heartbeat history: %w", err)
+	}
+	if limit <= 0 {
+		limit = DefaultHistorySize
+	}
+
+	return &History{
+		store: store,
+		limit: limit,
+	}, nil
+}
+
+// Add stores a heartbeat result, dropping the oldest results beyond the limit
+func (h *History) Add(record Record) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat record: %w", err)
+	}
+
+	return h.store.Update(func(tx storage.Tx) error {
+		if err := tx.Put(db.BucketHeartbeats, storage.SequenceKey(uint64(record.At.UnixNano())), value); err != nil {
+			return err
+		}
+
+		count, err := tx.Count(db.BucketHeartbeats)
+		if err != nil || count <= h.limit {
+			return err
+		}
+
+		// Keys sort by time, so the first ones are the oldest
+		var stale []string
+		tx.ForEach(db.BucketHeartbeats, func(key string, _ []byte) error {
+			if len(stale) >= count-h.limit {
+				return errStopWalk
+			}
+			stale = append(stale, key)
+			return nil
+		})
+		for _, key := range stale {
+			if err := tx.Delete(db.BucketHeartbeats, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Recent returns up to n of the latest heartbeat results, newest first
+func (h *History) Recent(n int) ([]Record, error) {
+	var records []Record
+	err := h.store.View(func(tx storage.Tx) error {
+		return tx.ForEach(db.BucketHeartbeats, func(_ string, value []byte) error {
+			var record Record
+			if err := json.Unmarshal(value, &record); err == nil {
+				records = append(records, record)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read heartbeat history: %w", err)
+	}
+
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	if n > 0 && len(records) > n {
+		records = records[:n]
+	}
+
+	return records, nil
+}
+
+// errStopWalk ends a ForEach early
+var errStopWalk = errors.New("stop walk")
@@ -3,8 +3,11 @@ package heartbeat
 import (
 	"context"
 	"log"
+	"mnemoCast-client/internal/cadence"
 	"mnemoCast-client/internal/client"
 	"mnemoCast-client/internal/identity"
+	"mnemoCast-client/internal/models"
+	"mnemoCast-client/internal/retry"
 	"sync"
 	"time"
 )
@@ -17,6 +20,8 @@ const (
 	StatusConnected
 	StatusDisconnected
 	StatusError
+	StatusAuthFailed    // Server rejected the screen's credentials (401/403)
+	StatusNotRegistered // Server doesn't know the screen ID (404)
 )
 
 func (s Status) String() string {
@@ -27,6 +32,10 @@ func (s Status) String() string {
 		return "Disconnected"
 	case StatusError:
 		return "Error"
+	case StatusAuthFailed:
+		return "Authentication Failed"
+	case StatusNotRegistered:
+		return "Not Registered"
 	default:
 		return "Unknown"
 	}
@@ -38,8 +47,7 @@ type Scheduler struct {
 	identityManager *identity.Manager
 	screenID        string
 	interval        time.Duration
-	retryAttempts   int
-	retryDelay      time.Duration
+	cadence         *cadence.Cadence // Effective interval, adjusted by server hints and Retry-After
 
 	ctx      context.Context
 	cancel   context.CancelFunc
@@ -48,16 +56,16 @@ type Scheduler struct {
 	status   Status
 	lastSent time.Time
 	lastError error
+	history  *History // Optional; records every heartbeat result
 }
 
 // NewScheduler creates a new heartbeat scheduler
+// Retries are governed by the retry policy configured on adClient
 func NewScheduler(
 	adClient *client.Client,
 	identityManager *identity.Manager,
 	screenID string,
 	intervalSeconds int,
-	retryAttempts int,
-	retryDelaySeconds int,
 ) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -66,14 +74,28 @@ func NewScheduler(
 		identityManager: identityManager,
 		screenID:        screenID,
 		interval:        time.Duration(intervalSeconds) * time.Second,
-		retryAttempts:   retryAttempts,
-		retryDelay:      time.Duration(retryDelaySeconds) * time.Second,
+		cadence:         cadence.New(time.Duration(intervalSeconds)*time.Second, 0, 0),
 		ctx:             ctx,
 		cancel:          cancel,
 		status:          StatusUnknown,
 	}
 }
 
+// SetHistory records heartbeat results in history; must be called before Start
+func (s *Scheduler) SetHistory(history *History) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = history
+}
+
+// SetIntervalBounds limits the interval the server may request; must be called before Start
+// Values <= 0 use the defaults
+func (s *Scheduler) SetIntervalBounds(min, max time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cadence = cadence.New(s.interval, min, max)
+}
+
 // Start starts the heartbeat scheduler
 func (s *Scheduler) Start() {
 	s.wg.Add(1)
@@ -97,90 +119,126 @@ func (s *Scheduler) run() {
 	log.Printf("[%s] [INIT] Sending initial heartbeat...", time.Now().Format("15:04:05.000"))
 	s.sendHeartbeat()
 
-	// Create ticker for periodic heartbeats
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
+	// The wait is recomputed after every heartbeat, since the server may change it
+	timer := time.NewTimer(s.cadence.Next())
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-s.ctx.Done():
 			log.Printf("[%s] [SHUTDOWN] Heartbeat scheduler stopping...", time.Now().Format("15:04:05.000"))
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			tickTime := time.Now()
 			log.Printf("[%s] [TIMER] Heartbeat interval reached (every %v), sending heartbeat...", 
-				tickTime.Format("15:04:05.000"), s.interval)
+				tickTime.Format("15:04:05.000"), s.cadence.Interval())
 			s.sendHeartbeat()
+			timer.Reset(s.cadence.Next())
 		}
 	}
 }
 
-// sendHeartbeat sends a heartbeat with retry logic
+// sendHeartbeat sends a heartbeat
+// Transient failures are retried inside the client using the shared retry policy
 func (s *Scheduler) sendHeartbeat() {
 	startTime := time.Now()
 	log.Printf("[%s] [HB] Starting heartbeat cycle for screen: %s", startTime.Format("15:04:05.000"), s.screenID)
-	
-	var lastErr error
-
-	// Retry logic
-	for attempt := 0; attempt <= s.retryAttempts; attempt++ {
-		if attempt > 0 {
-			// Exponential backoff
-			delay := s.retryDelay * time.Duration(attempt)
-			retryTime := time.Now()
-			log.Printf("[%s] [RETRY] Retrying heartbeat (attempt %d/%d) after %v...", 
-				retryTime.Format("15:04:05.000"), attempt, s.retryAttempts, delay)
-			time.Sleep(delay)
-		}
 
-		attemptTime := time.Now()
-		log.Printf("[%s] [ATTEMPT] Attempting heartbeat (attempt %d/%d)...", 
-			attemptTime.Format("15:04:05.000"), attempt+1, s.retryAttempts+1)
-		
-		err := s.client.Heartbeat(s.screenID)
-		if err == nil {
-			// Success
-			successTime := time.Now()
-			totalDuration := successTime.Sub(startTime)
-			
-			s.mu.Lock()
-			s.status = StatusConnected
-			s.lastSent = time.Now()
-			s.lastError = nil
-			s.mu.Unlock()
-
-			// Update last seen in identity
-			if identity, err := s.identityManager.LoadIdentity(); err == nil {
-				_ = s.identityManager.UpdateLastSeen(identity)
-			}
-
-			if attempt > 0 {
-				log.Printf("[%s] [OK] Heartbeat succeeded after %d retries | Total duration: %v", 
-					successTime.Format("15:04:05.000"), attempt, totalDuration)
-			} else {
-				log.Printf("[%s] [OK] Heartbeat cycle completed successfully | Total duration: %v", 
-					successTime.Format("15:04:05.000"), totalDuration)
-			}
+	resp, err := s.client.HeartbeatWithResponse(s.ctx, s.screenID)
+	if err != nil {
+		if s.ctx.Err() != nil {
+			log.Printf("[%s] [SHUTDOWN] Heartbeat cycle cancelled", time.Now().Format("15:04:05.000"))
 			return
 		}
 
-		lastErr = err
-		errorTime := time.Now()
-		log.Printf("[%s] [WARN] Heartbeat attempt %d/%d failed: %v", 
-			errorTime.Format("15:04:05.000"), attempt+1, s.retryAttempts+1, err)
+		failureTime := time.Now()
+		totalDuration := failureTime.Sub(startTime)
+
+		// Credential problems won't be fixed by retrying, so report them distinctly
+		status := StatusError
+		switch {
+		case client.IsAuthError(err):
+			status = StatusAuthFailed
+			log.Printf("[%s] [AUTH] Heartbeat rejected: check the screen ID and passkey", failureTime.Format("15:04:05.000"))
+		case client.IsNotFoundError(err):
+			status = StatusNotRegistered
+			log.Printf("[%s] [AUTH] Screen %s is not registered on the server", failureTime.Format("15:04:05.000"), s.screenID)
+		}
+
+		s.mu.Lock()
+		s.status = status
+		s.lastError = err
+		s.mu.Unlock()
+		s.applyRetryAfter(err)
+		s.recordHistory(failureTime, status, totalDuration, err)
+
+		log.Printf("[%s] [ERROR] Heartbeat cycle failed | Total duration: %v | Error: %v", 
+			failureTime.Format("15:04:05.000"), totalDuration, err)
+		return
 	}
 
-	// All retries failed
-	failureTime := time.Now()
-	totalDuration := failureTime.Sub(startTime)
+	// Success
+	successTime := time.Now()
+	totalDuration := successTime.Sub(startTime)
 	
 	s.mu.Lock()
-	s.status = StatusError
-	s.lastError = lastErr
+	s.status = StatusConnected
+	s.lastSent = time.Now()
+	s.lastError = nil
 	s.mu.Unlock()
+	s.recordHistory(successTime, StatusConnected, totalDuration, nil)
+	s.applyHint(resp)
+
+	// Update last seen in identity
+	if identity, err := s.identityManager.LoadIdentity(); err == nil {
+		_ = s.identityManager.UpdateLastSeen(identity)
+	}
+
+	log.Printf("[%s] [OK] Heartbeat cycle completed successfully | Total duration: %v", 
+		successTime.Format("15:04:05.000"), totalDuration)
+}
 
-	log.Printf("[%s] [ERROR] Heartbeat cycle failed after %d attempts | Total duration: %v | Error: %v", 
-		failureTime.Format("15:04:05.000"), s.retryAttempts+1, totalDuration, lastErr)
+// applyHint adopts the heartbeat interval requested by the server, or the configured one if none
+func (s *Scheduler) applyHint(resp *models.HeartbeatResponse) {
+	hint := time.Duration(resp.NextHeartbeatAfter) * time.Second
+	previous := s.cadence.Interval()
+	s.cadence.SetHint(hint)
+	if current := s.cadence.Interval(); current != previous {
+		log.Printf("[%s] [HB] Heartbeat interval changed by server: %v -> %v", 
+			time.Now().Format("15:04:05.000"), previous, current)
+	}
+}
+
+// applyRetryAfter delays the next heartbeat if the server asked for a longer pause than the retries allowed
+func (s *Scheduler) applyRetryAfter(err error) {
+	if wait := retry.RetryAfter(err); wait > 0 {
+		s.cadence.Defer(wait)
+		log.Printf("[%s] [HB] Server asked to retry after %v, delaying next heartbeat", 
+			time.Now().Format("15:04:05.000"), wait)
+	}
+}
+
+// recordHistory stores the result of a heartbeat cycle if a history is set
+func (s *Scheduler) recordHistory(at time.Time, status Status, duration time.Duration, err error) {
+	s.mu.RLock()
+	history := s.history
+	s.mu.RUnlock()
+	if history == nil {
+		return
+	}
+
+	record := Record{
+		At:         at,
+		Status:     status.String(),
+		DurationMs: duration.Milliseconds(),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if err := history.Add(record); err != nil {
+		log.Printf("[%s] [HB] [WARN] Failed to record heartbeat history: %v", time.Now().Format("15:04:05.000"), err)
+	}
 }
 
 // GetStatus returns the current connection status
@@ -218,9 +276,19 @@ func (s *Scheduler) GetStats() map[string]interface{} {
 		"status":    s.status.String(),
 		"lastSent":  s.lastSent,
 		"interval":  s.interval.String(),
+		"effectiveInterval": s.cadence.Interval().String(),
 		"connected": s.status == StatusConnected,
 	}
 
+	if next := s.cadence.NextRun(); !next.IsZero() {
+		stats["nextHeartbeat"] = next
+	}
+
+	// Which ad server endpoint is serving the screen, and the health of the others
+	stats["activeEndpoint"] = s.client.ActiveEndpoint()
+	stats["endpoints"] = s.client.EndpointStatus()
+	stats["endpointSwitches"] = s.client.EndpointSwitches()
+
 	if s.lastError != nil {
 		stats["lastError"] = s.lastError.Error()
 	}
@@ -0,0 +1,48 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mnemoCast-client/internal/client"
+	"mnemoCast-client/internal/models"
+	"mnemoCast-client/internal/retry"
+)
+
+func TestGetStatsReportsActiveEndpoint(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	primary, backup := httptest.NewServer(ok), httptest.NewServer(ok)
+	defer backup.Close()
+	primary.Close()
+
+	adClient := client.NewClient(primary.URL, "screen-1", "passkey")
+	adClient.SetRetryPolicy(retry.NewPolicy(1, time.Millisecond, time.Millisecond))
+	adClient.SetEndpoints([]models.ServerEndpoint{
+		{URL: primary.URL, Priority: 10},
+		{URL: backup.URL},
+	}, time.Minute)
+	scheduler := NewScheduler(adClient, nil, "screen-1", 30)
+
+	if got := scheduler.GetStats()["activeEndpoint"]; got != primary.URL {
+		t.Fatalf("activeEndpoint = %v, want %s before any request", got, primary.URL)
+	}
+
+	if _, err := adClient.HeartbeatWithResponse(context.Background(), "screen-1"); err != nil {
+		t.Fatalf("heartbeat should succeed on the backup: %v", err)
+	}
+
+	stats := scheduler.GetStats()
+	if got := stats["activeEndpoint"]; got != backup.URL {
+		t.Errorf("activeEndpoint = %v, want %s", got, backup.URL)
+	}
+	if got := stats["endpointSwitches"]; got != 1 {
+		t.Errorf("endpointSwitches = %v, want 1", got)
+	}
+	endpoints, isStatus := stats["endpoints"].([]client.EndpointStatus)
+	if !isStatus || len(endpoints) != 2 || endpoints[0].Healthy || !endpoints[1].Active {
+		t.Errorf("endpoints = %+v, want the primary down and the backup active", stats["endpoints"])
+	}
+}
@@ -2,23 +2,44 @@ package identity
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mnemoCast-client/internal/db"
 	"mnemoCast-client/internal/models"
-	"os"
+	"mnemoCast-client/pkg/storage"
 	"path/filepath"
 	"time"
 )
 
+// identityKey is the key of the screen identity in the identity bucket
+const identityKey = "screen"
+
 // Manager handles screen identity operations
+// The identity lives in the screen database; identity.json is still read as an import path
 type Manager struct {
 	configDir string
+
+	store    storage.Store
+	storeErr error // Set if the database could not be opened
 }
 
 // NewManager creates a new identity manager
 func NewManager(configDir string) *Manager {
-	return &Manager{
+	m := &Manager{
 		configDir: configDir,
 	}
+
+	m.store, m.storeErr = db.Open(configDir)
+	if m.storeErr != nil {
+		fmt.Printf("Warning: Failed to open identity database: %v\n", m.storeErr)
+		return m
+	}
+
+	if err := m.importLegacy(); err != nil {
+		fmt.Printf("Warning: Failed to import identity.json: %v\n", err)
+	}
+
+	return m
 }
 
 // GetOrCreateIdentity loads existing identity
@@ -35,56 +56,28 @@ func (m *Manager) GetOrCreateIdentity() (*models.ScreenIdentity, error) {
 	return nil, fmt.Errorf("identity not found - connect to server first to load screen identity")
 }
 
-// LoadIdentity loads the screen identity from file
+// LoadIdentity loads the screen identity
 func (m *Manager) LoadIdentity() (*models.ScreenIdentity, error) {
-	identityFile := filepath.Join(m.configDir, "identity.json")
-	
-	data, err := os.ReadFile(identityFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("identity file not found")
-		}
-		return nil, fmt.Errorf("failed to read identity file: %w", err)
+	if m.storeErr != nil {
+		return nil, m.storeErr
 	}
 
 	var identity models.ScreenIdentity
-	if err := json.Unmarshal(data, &identity); err != nil {
-		return nil, fmt.Errorf("failed to parse identity: %w", err)
-	}
-
-	// Migrate old format if needed (handle nested Location structure)
-	var oldFormat struct {
-		ID            string    `json:"id"`
-		Name          string    `json:"name"`
-		Location      struct {
-			City      string `json:"city"`
-			Area      string `json:"area"`
-			VenueType string `json:"venueType"`
-			Address   string `json:"address,omitempty"`
-		} `json:"location"`
-		Classification int       `json:"classification"`
-		CreatedAt      time.Time `json:"createdAt"`
-		LastSeen       time.Time `json:"lastSeen"`
-	}
-	
-	// Try to parse as old format
-	if err := json.Unmarshal(data, &oldFormat); err == nil && oldFormat.Location.City != "" {
-		// Migrate from old format
-		identity.Country = "Unknown"
-		identity.City = oldFormat.Location.City
-		identity.Area = oldFormat.Location.Area
-		identity.VenueType = oldFormat.Location.VenueType
-		if identity.Timezone == "" {
-			identity.Timezone = "UTC"
+	err := m.store.View(func(tx storage.Tx) error {
+		data, err := tx.Get(db.BucketIdentity, identityKey)
+		if err != nil {
+			return err
 		}
-		if identity.Width == 0 {
-			identity.Width = 1920
+		if err := json.Unmarshal(data, &identity); err != nil {
+			return fmt.Errorf("failed to parse identity: %w", err)
 		}
-		if identity.Height == 0 {
-			identity.Height = 1080
-		}
-		// Save migrated identity
-		m.SaveIdentity(&identity)
+		return nil
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("no identity stored")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity: %w", err)
 	}
 
 	// Validate identity
@@ -95,28 +88,79 @@ func (m *Manager) LoadIdentity() (*models.ScreenIdentity, error) {
 	return &identity, nil
 }
 
-// SaveIdentity saves the screen identity to file
+// SaveIdentity saves the screen identity
 func (m *Manager) SaveIdentity(identity *models.ScreenIdentity) error {
-	// Ensure config directory exists
-	if err := os.MkdirAll(m.configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	if m.storeErr != nil {
+		return m.storeErr
 	}
 
-	identityFile := filepath.Join(m.configDir, "identity.json")
-	
-	data, err := json.MarshalIndent(identity, "", "  ")
+	data, err := json.Marshal(identity)
 	if err != nil {
 		return fmt.Errorf("failed to marshal identity: %w", err)
 	}
 
-	// Write with restricted permissions
-	if err := os.WriteFile(identityFile, data, 0600); err != nil {
-		return fmt.Errorf("failed to write identity file: %w", err)
+	err = m.store.Update(func(tx storage.Tx) error {
+		return tx.Put(db.BucketIdentity, identityKey, data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write identity: %w", err)
 	}
 
 	return nil
 }
 
+// importLegacy imports identity.json into the database when it is new or has been replaced
+// Identities in the old nested-location format are migrated on the way in
+func (m *Manager) importLegacy() error {
+	identityFile := filepath.Join(m.configDir, "identity.json")
+
+	_, err := storage.ImportFile(m.store, identityFile, func(tx storage.Tx, data []byte) error {
+		var identity models.ScreenIdentity
+		if err := json.Unmarshal(data, &identity); err != nil {
+			return fmt.Errorf("failed to parse identity: %w", err)
+		}
+
+		// Migrate old format if needed (handle nested Location structure)
+		var oldFormat struct {
+			ID            string    `json:"id"`
+			Name          string    `json:"name"`
+			Location      struct {
+				City      string `json:"city"`
+				Area      string `json:"area"`
+				VenueType string `json:"venueType"`
+				Address   string `json:"address,omitempty"`
+			} `json:"location"`
+			Classification int       `json:"classification"`
+			CreatedAt      time.Time `json:"createdAt"`
+			LastSeen       time.Time `json:"lastSeen"`
+		}
+		
+		// Try to parse as old format
+		if err := json.Unmarshal(data, &oldFormat); err == nil && oldFormat.Location.City != "" {
+			identity.Country = "Unknown"
+			identity.City = oldFormat.Location.City
+			identity.Area = oldFormat.Location.Area
+			identity.VenueType = oldFormat.Location.VenueType
+			if identity.Timezone == "" {
+				identity.Timezone = "UTC"
+			}
+			if identity.Width == 0 {
+				identity.Width = 1920
+			}
+			if identity.Height == 0 {
+				identity.Height = 1080
+			}
+		}
+
+		encoded, err := json.Marshal(&identity)
+		if err != nil {
+			return fmt.Errorf("failed to marshal identity: %w", err)
+		}
+		return tx.Put(db.BucketIdentity, identityKey, encoded)
+	})
+	return err
+}
+
 // CreateIdentityFromServer creates identity from server response
 // This is called after successful connection to server
 func (m *Manager) CreateIdentityFromServer(screen *models.Screen) (*models.ScreenIdentity, error) {
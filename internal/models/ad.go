@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Ad represents an advertisement delivered to the screen
 type Ad struct {
@@ -8,17 +11,78 @@ type Ad struct {
 	Title       string    `json:"title,omitempty"`       // Ad title
 	Type        string    `json:"type"`                  // Ad type (image, video, html, etc.)
 	ContentURL  string    `json:"contentUrl"`            // URL to ad content
+	Checksum    string    `json:"checksum,omitempty"`    // SHA-256 of the content, hex ("sha256:" prefix optional)
+	Size        int64     `json:"size,omitempty"`        // Content size in bytes
 	Duration    int       `json:"duration,omitempty"`    // Display duration in seconds
 	StartTime   time.Time `json:"startTime,omitempty"`   // Scheduled start time
 	EndTime     time.Time `json:"endTime,omitempty"`     // Scheduled end time
 	Priority    int       `json:"priority,omitempty"`    // Display priority
+	MaxOfflineAge int     `json:"maxOfflineAge,omitempty"` // Seconds the ad may keep running without the server confirming the playlist
+	Source      string    `json:"source,omitempty"`      // Ad source the ad came from, set by the client when playlists are merged
 	Metadata    map[string]interface{} `json:"metadata,omitempty"` // Additional metadata
 }
 
+// SHA256 returns the ad's content checksum as lowercase hex, or "" if none was given
+func (a *Ad) SHA256() string {
+	sum := strings.ToLower(strings.TrimSpace(a.Checksum))
+	return strings.TrimPrefix(sum, "sha256:")
+}
+
 // AdDeliveryResponse represents the response from the ad delivery endpoint
 type AdDeliveryResponse struct {
 	Ads       []Ad       `json:"ads"`                    // List of ads to display
 	PlaylistID string    `json:"playlistId,omitempty"`   // Associated playlist ID
+	ScreenID  string     `json:"screenId,omitempty"`     // Screen the playlist was issued for; required in signed playlists
 	UpdatedAt time.Time  `json:"updatedAt"`              // Last update timestamp
+	NextFetchAfter int   `json:"nextFetchAfter,omitempty"` // Seconds until the screen should fetch again (server hint)
+	ExpiresAt time.Time  `json:"expiresAt,omitempty"`    // When the playlist should be refreshed at the latest
+
+	// How merged playlists share airtime between ad sources; empty for a single source
+	Sources []SourceWeight `json:"sources,omitempty"`
+
+	// Set when the playlist arrived in a signed envelope; not part of the playlist JSON
+	Signature *PlaylistSignature `json:"-"`
+}
+
+// PlaylistSignature is the signed form a playlist was delivered in
+// The payload is kept byte-for-byte so the signature can be checked again after loading
+type PlaylistSignature struct {
+	Payload   []byte `json:"payload"`         // Playlist JSON exactly as signed
+	Signature []byte `json:"signature"`       // Ed25519 signature of Payload
+	KeyID     string `json:"keyId,omitempty"` // Signing key named by the server
+}
+
+
+// SourceWeight is the share of airtime an ad source gets in a merged playlist
+// Only sources in the highest priority with playable ads play; they split airtime by weight
+type SourceWeight struct {
+	Name     string `json:"name"`
+	Weight   int    `json:"weight"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// AdRejection records an ad dropped from a playlist because it failed validation
+type AdRejection struct {
+	AdID   string `json:"adId"`   // ID as delivered, may be empty
+	Index  int    `json:"index"`  // Position in the delivered playlist
+	Reason string `json:"reason"` // Why the ad was rejected
+}
+
+// AdRejectionReport tells the server which ads of a playlist the screen refused to play
+type AdRejectionReport struct {
+	PlaylistID string        `json:"playlistId,omitempty"`
+	Rejected   []AdRejection `json:"rejected"`
+	ReportedAt time.Time     `json:"reportedAt"`
+}
+
+// CacheValidators holds the HTTP validators of the last ad delivery response
+// They are sent back as If-None-Match / If-Modified-Since to skip unchanged playlists
+type CacheValidators struct {
+	ETag         string `json:"etag,omitempty"`         // ETag response header
+	LastModified string `json:"lastModified,omitempty"` // Last-Modified response header
 }
 
+// IsEmpty reports whether no validators are available
+func (v *CacheValidators) IsEmpty() bool {
+	return v == nil || (v.ETag == "" && v.LastModified == "")
+}
@@ -6,10 +6,86 @@ import "time"
 type ScreenConfig struct {
 	Identity         ScreenIdentity `json:"identity"`
 	AdServerURL      string         `json:"adServerUrl"`      // Backend URL
+	AdServerURLs     []ServerEndpoint `json:"adServerUrls,omitempty"` // Backend endpoints to fail over between; replaces adServerUrl when set
+	EndpointHealthInterval int     `json:"endpointHealthInterval"` // Seconds between health checks of the backend endpoints (-1 = disabled)
+	EndpointFailBackDelay  int     `json:"endpointFailBackDelay"`  // Seconds a preferred endpoint must stay healthy before switching back to it (-1 = immediately)
 	HeartbeatInterval int          `json:"heartbeatInterval"` // Seconds between heartbeats
 	AdFetchInterval   int          `json:"adFetchInterval"`   // Seconds between ad fetches
+	PushEnabled      bool          `json:"pushEnabled"`       // Receive playlist updates over the server event stream
+	MinPollInterval  int           `json:"minPollInterval"`   // Shortest fetch/heartbeat interval in seconds the server may request
+	MaxPollInterval  int           `json:"maxPollInterval"`   // Longest fetch/heartbeat interval (and Retry-After) in seconds the server may request
+	AuthMode         string        `json:"authMode,omitempty"` // "passkey" (default) or "hmac" to sign requests instead of sending the passkey
+	PlaylistPublicKey string       `json:"playlistPublicKey,omitempty"` // Ed25519 key (base64 or hex) playlists must be signed with; empty accepts unsigned playlists
 	RetryAttempts    int           `json:"retryAttempts"`     // Max retry attempts
-	RetryDelay       int           `json:"retryDelay"`        // Seconds between retries
+	RetryDelay       int           `json:"retryDelay"`        // Base delay in seconds before the first retry
+	RetryMaxDelay    int           `json:"retryMaxDelay"`     // Cap in seconds for exponential retry backoff
+	ProofOfPlayUploadInterval int  `json:"proofOfPlayUploadInterval"` // Seconds between proof-of-play uploads
+	ProofOfPlayBatchSize      int  `json:"proofOfPlayBatchSize"`      // Max records per upload request
+	EventQueueMaxSize         int  `json:"eventQueueMaxSize"`         // Max queued records before the oldest are dropped
+	MaxAdResponseSize         int64 `json:"maxAdResponseSize"`        // Max decoded ad delivery response in bytes
+	PlaylistHistorySize       int  `json:"playlistHistorySize"`       // Playlist snapshots kept for rollback (-1 = no history)
+	DownloadWorkers           int  `json:"downloadWorkers"`           // Media downloads running in parallel
+	DownloadsPerHost          int  `json:"downloadsPerHost"`          // Max parallel media downloads from one host
+	PrefetchConcurrency       int  `json:"prefetchConcurrency,omitempty"` // Deprecated: older name of downloadWorkers, moved across by the config loader
+	PrefetchReadyFraction     float64 `json:"prefetchReadyFraction"`  // Fraction of media (0-1] that must be cached before a new playlist plays
+	PrefetchTimeout           int  `json:"prefetchTimeout"`           // Seconds to wait for media before playing a new playlist anyway
+	DownloadStallTimeout      int  `json:"downloadStallTimeout"`      // Seconds without data before a media download is retried
+	DownloadBandwidthLimit    int64 `json:"downloadBandwidthLimit"`   // Max combined media download rate in bytes per second (0 = unlimited)
+	MediaCacheQuota           int64 `json:"mediaCacheQuota"`          // Max bytes of cached media before least recently played files are evicted
+	MinFreeDiskSpace          int64 `json:"minFreeDiskSpace"`         // Bytes to keep free on the media disk, evicting media if needed
+	MaxOfflineAge             int  `json:"maxOfflineAge"`             // Seconds ads keep running without the server confirming the playlist (0 = unlimited)
+	FallbackPlaylist          string `json:"fallbackPlaylist,omitempty"` // Playlist file (relative to the ads directory) played when offline ads expire
+	AdSources        []AdSourceConfig `json:"adSources,omitempty"` // Extra ad sources merged with the ad server's playlist
+	Transport        TransportConfig `json:"transport"`       // TLS, proxy and timeout settings for all HTTP traffic
+}
+
+// ServerEndpoint is one ad server endpoint
+type ServerEndpoint struct {
+	URL      string `json:"url"`
+	Priority int    `json:"priority,omitempty"` // Higher priorities are preferred; ties keep config order
+}
+
+// ServerEndpoints returns the ad server endpoints, falling back to adServerUrl
+func (c *ScreenConfig) ServerEndpoints() []ServerEndpoint {
+	if len(c.AdServerURLs) > 0 {
+		return c.AdServerURLs
+	}
+	return []ServerEndpoint{{URL: c.AdServerURL}}
+}
+
+// Ad source types for AdSourceConfig.Type
+const (
+	AdSourceAdServer  = "adserver"  // The main ad server; an entry of this type only sets its weight and priority
+	AdSourceServer    = "server"    // A secondary ad server speaking the ad delivery API
+	AdSourceDirectory = "directory" // Media files in a local directory, one ad per file
+	AdSourceFile      = "file"      // A playlist file in the ad delivery response format
+)
+
+// AdSourceConfig configures one ad source
+type AdSourceConfig struct {
+	Name     string `json:"name"`               // Unique name, recorded on each ad and in proof of play
+	Type     string `json:"type"`               // adserver, server, directory or file
+	URL      string `json:"url,omitempty"`      // Base URL of a secondary server
+	ScreenID string `json:"screenId,omitempty"` // Screen ID on a secondary server (default: this screen's ID)
+	Passkey  string `json:"passkey,omitempty"`  // Passkey on a secondary server (default: this screen's passkey)
+	Path     string `json:"path,omitempty"`     // Directory or playlist file, relative to the ads directory
+	Interval int    `json:"interval,omitempty"` // Seconds between fetches (default: adFetchInterval)
+	Weight   int    `json:"weight,omitempty"`   // Share of airtime among sources of the same priority (default 1)
+	Priority int    `json:"priority,omitempty"` // Higher priorities play first; lower ones only fill in when they have nothing to play
+}
+
+// TransportConfig configures the HTTP transport shared by the API client and media downloader
+// Zero values fall back to Go defaults (system CAs, proxy from environment, TLS 1.2)
+type TransportConfig struct {
+	CAFile                string `json:"caFile,omitempty"`                // PEM CA bundle; when set only these CAs are trusted
+	ClientCertFile        string `json:"clientCertFile,omitempty"`        // PEM client certificate for mutual TLS
+	ClientKeyFile         string `json:"clientKeyFile,omitempty"`         // PEM client private key for mutual TLS
+	ProxyURL              string `json:"proxyUrl,omitempty"`              // Explicit proxy (http://, https:// or socks5://)
+	TLSMinVersion         string `json:"tlsMinVersion,omitempty"`         // Minimum TLS version: "1.2" or "1.3"
+	DialTimeout           int    `json:"dialTimeout,omitempty"`           // Seconds to establish a TCP connection
+	TLSHandshakeTimeout   int    `json:"tlsHandshakeTimeout,omitempty"`   // Seconds to complete the TLS handshake
+	ResponseHeaderTimeout int    `json:"responseHeaderTimeout,omitempty"` // Seconds to wait for response headers
+	RequestTimeout        int    `json:"requestTimeout,omitempty"`        // Total seconds per API request
 }
 
 // DefaultConfig returns a default configuration
@@ -34,8 +110,31 @@ func DefaultConfig() *ScreenConfig {
 		AdServerURL:      "http://10.42.0.1:8080",
 		HeartbeatInterval: 30,
 		AdFetchInterval:   60, // Fetch ads every 60 seconds (1 minute)
+		EndpointHealthInterval: 30,
+		EndpointFailBackDelay:  120,
+		MinPollInterval:   10,
+		MaxPollInterval:   3600,
 		RetryAttempts:    3,
 		RetryDelay:       5,
+		RetryMaxDelay:    60,
+		ProofOfPlayUploadInterval: 60,
+		ProofOfPlayBatchSize:      100,
+		EventQueueMaxSize:         50000,
+		MaxAdResponseSize:         10 << 20,
+		PlaylistHistorySize:       10,
+		DownloadWorkers:           3,
+		DownloadsPerHost:          2,
+		PrefetchReadyFraction:     1.0,
+		PrefetchTimeout:           600,
+		DownloadStallTimeout:      30,
+		MediaCacheQuota:           2 << 30,
+		MinFreeDiskSpace:          256 << 20,
 	}
 }
 
+
+// Authentication modes for ScreenConfig.AuthMode
+const (
+	AuthModePasskey = "passkey"
+	AuthModeHMAC    = "hmac"
+)
@@ -1,10 +1,13 @@
 package models
 
+import "time"
+
 // Credentials represents authentication credentials for the screen
 // Screen ID and Passkey are server-assigned and must be configured manually
 type Credentials struct {
 	ScreenID string `json:"screenId"`  // Server-assigned screen ID (NOT NULL)
 	Passkey  string `json:"passkey"`    // Server-assigned passkey (NOT NULL)
+	RefreshToken string `json:"refreshToken,omitempty"` // Session refresh token issued on connect
 }
 
 // HasCredentials checks if both screen ID and passkey are present
@@ -17,3 +20,17 @@ func (c *Credentials) IsValid() bool {
 	return c.HasCredentials()
 }
 
+// SessionToken is a short-lived bearer token issued by the server in exchange for the passkey
+type SessionToken struct {
+	AccessToken  string    `json:"accessToken"`            // Bearer token for API calls
+	ExpiresAt    time.Time `json:"expiresAt,omitempty"`    // Access token expiry (zero if unknown)
+	RefreshToken string    `json:"refreshToken,omitempty"` // Long-lived token used to get new access tokens
+}
+
+// IsValid reports whether the access token is present and won't expire within margin
+func (t *SessionToken) IsValid(now time.Time, margin time.Duration) bool {
+	if t.AccessToken == "" {
+		return false
+	}
+	return t.ExpiresAt.IsZero() || now.Add(margin).Before(t.ExpiresAt)
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// PlayOutcome describes how an ad playback ended
+type PlayOutcome string
+
+const (
+	PlayOutcomeCompleted   PlayOutcome = "completed"    // Played for its full scheduled duration
+	PlayOutcomeInterrupted PlayOutcome = "interrupted"  // Cut short (player stopped or paused)
+	PlayOutcomeRenderError PlayOutcome = "render_error" // Renderer failed to display the ad
+	PlayOutcomeNoMedia     PlayOutcome = "no_media"     // Skipped because media could not be obtained
+)
+
+// PlayRecord is a proof-of-play record for a single ad playback
+type PlayRecord struct {
+	ID         string      `json:"id"`                   // Unique record ID (used for acknowledgement)
+	AdID       string      `json:"adId"`                 // Played ad
+	PlaylistID string      `json:"playlistId,omitempty"` // Playlist the ad came from
+	Source     string      `json:"source,omitempty"`     // Ad source the ad came from in a merged playlist
+	StartedAt  time.Time   `json:"startedAt"`            // When the ad appeared on screen
+	EndedAt    time.Time   `json:"endedAt"`              // When the ad left the screen
+	DurationMs int64       `json:"durationMs"`           // Actual time on screen in milliseconds
+	Renderer   string      `json:"renderer,omitempty"`   // Renderer used (image, video, html, text)
+	Outcome    PlayOutcome `json:"outcome"`              // How the playback ended
+	QueuedAt   time.Time   `json:"queuedAt"`             // When the record was written to the queue
+}
+
+// ProofOfPlayUploadRequest is the batch upload body for proof-of-play records
+type ProofOfPlayUploadRequest struct {
+	Records []PlayRecord `json:"records"`
+}
+
+// ProofOfPlayAck is the server acknowledgement of an upload batch
+// Both accepted and rejected records are removed from the local queue
+type ProofOfPlayAck struct {
+	Accepted []string `json:"accepted"`           // Record IDs stored by the server
+	Rejected []string `json:"rejected,omitempty"` // Record IDs the server will never accept (e.g. malformed)
+}
@@ -46,5 +46,6 @@ type HeartbeatRequest struct {
 type HeartbeatResponse struct {
 	Message   string `json:"message"`
 	Timestamp string `json:"timestamp"`
+	NextHeartbeatAfter int `json:"nextHeartbeatAfter,omitempty"` // Seconds until the next heartbeat (server hint)
 }
 
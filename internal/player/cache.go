@@ -0,0 +1,300 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mnemoCast-client/internal/ads"
+	"mnemoCast-client/internal/models"
+	"mnemoCast-client/pkg/storage"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache limits used when the config leaves them unset
+const (
+	DefaultMediaCacheQuota  = 2 << 30   // 2 GiB
+	DefaultMinFreeDiskSpace = 256 << 20 // 256 MiB
+)
+
+// cacheGracePeriod protects freshly downloaded media from eviction, since a prefetch
+// may have fetched it after the caller collected the ads in use
+const cacheGracePeriod = time.Minute
+
+// abandonedDirGracePeriod is how long a per-ad media directory must go unchanged before it is
+// removed as abandoned, so one test-ads just created for the next playlist survives
+const abandonedDirGracePeriod = 24 * time.Hour
+
+// CacheStats describes the media cache after the last enforcement run
+type CacheStats struct {
+	Files     int
+	Bytes     int64
+	Quota     int64
+	FreeDisk  int64 // -1 if the platform can't report it
+	MinFree   int64
+	Evicted   int // Files evicted since start
+	LastCheck time.Time
+}
+
+// MediaCache keeps the media directory within a size quota and a free-disk threshold
+// Media not used by the active (or prefetching) playlist is evicted least recently played first
+type MediaCache struct {
+	storage *ads.Storage
+	quota   int64
+	minFree int64
+
+	mu    sync.Mutex
+	stats CacheStats
+}
+
+// cachedObject is a file in the object store with the time it was last used
+type cachedObject struct {
+	path     string
+	sum      string
+	size     int64
+	lastUsed time.Time
+	urls     []string // Index entries pointing at it
+}
+
+// NewMediaCache creates a cache manager; limits <= 0 use the defaults
+func NewMediaCache(storage *ads.Storage, quota, minFree int64) *MediaCache {
+	if quota <= 0 {
+		quota = DefaultMediaCacheQuota
+	}
+	if minFree <= 0 {
+		minFree = DefaultMinFreeDiskSpace
+	}
+	return &MediaCache{
+		storage: storage,
+		quota:   quota,
+		minFree: minFree,
+		stats: CacheStats{
+			Quota:    quota,
+			MinFree:  minFree,
+			FreeDisk: -1,
+		},
+	}
+}
+
+// Touch records that an ad's media was played
+func (c *MediaCache) Touch(ad *models.Ad) {
+	if err := c.storage.TouchMediaEntry(ad.ContentURL, time.Now()); err != nil {
+		log.Printf("[%s] [CACHE] [WARN] %v", time.Now().Format("15:04:05.000"), err)
+	}
+}
+
+// GetStats returns the cache statistics from the last enforcement run
+func (c *MediaCache) GetStats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Enforce removes abandoned files and evicts media until the cache fits its limits
+// Media of the given ads is never evicted, even if that leaves the cache over quota.
+// An empty inUse means the playlist isn't known (yet), so nothing but old partial downloads is removed.
+func (c *MediaCache) Enforce(inUse []models.Ad) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeAbandoned(inUse)
+	known := len(inUse) > 0
+
+	objects, err := c.scanObjects()
+	if err != nil {
+		return err
+	}
+
+	protected := c.protectedSums(inUse, objects)
+	var total int64
+	for _, object := range objects {
+		total += object.size
+	}
+	total += dirSize(c.storage.GetTempDir())
+
+	free, freeErr := storage.FreeSpace(c.storage.GetMediaDir())
+	if freeErr != nil {
+		if !errors.Is(freeErr, errors.ErrUnsupported) {
+			log.Printf("[%s] [CACHE] [WARN] Failed to read free disk space: %v", time.Now().Format("15:04:05.000"), freeErr)
+		}
+		free = -1
+	}
+
+	// Least recently used first
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].lastUsed.Before(objects[j].lastUsed)
+	})
+
+	cutoff := time.Now().Add(-cacheGracePeriod)
+	evicted := 0
+	var staleURLs []string
+	for _, object := range objects {
+		if !known || total <= c.quota && (free < 0 || free >= c.minFree) {
+			break
+		}
+		if protected[object.sum] || object.lastUsed.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(object.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("[%s] [CACHE] [WARN] Failed to evict %s: %v", time.Now().Format("15:04:05.000"), object.path, err)
+			continue
+		}
+		total -= object.size
+		if free >= 0 {
+			free += object.size
+		}
+		evicted++
+		staleURLs = append(staleURLs, object.urls...)
+		log.Printf("[%s] [CACHE] Evicted %s (%d bytes, last used %s)",
+			time.Now().Format("15:04:05.000"), filepath.Base(object.path), object.size, object.lastUsed.Format(time.RFC3339))
+	}
+	if err := c.storage.DeleteMediaEntries(staleURLs); err != nil {
+		return err
+	}
+
+	if !known && (total > c.quota || free >= 0 && free < c.minFree) {
+		log.Printf("[%s] [CACHE] [WARN] Media cache is over its limits, but no playlist is loaded to tell which media is in use",
+			time.Now().Format("15:04:05.000"))
+	} else if total > c.quota {
+		log.Printf("[%s] [CACHE] [WARN] Media cache is %d bytes, over its %d byte quota, but the rest is in use",
+			time.Now().Format("15:04:05.000"), total, c.quota)
+	}
+	if free >= 0 && free < c.minFree {
+		log.Printf("[%s] [CACHE] [WARN] Only %d bytes free on disk (minimum %d)",
+			time.Now().Format("15:04:05.000"), free, c.minFree)
+	}
+
+	c.stats.Files = len(objects) - evicted
+	c.stats.Bytes = total
+	c.stats.FreeDisk = free
+	c.stats.Evicted += evicted
+	c.stats.LastCheck = time.Now()
+	return nil
+}
+
+// scanObjects lists the object store with each file's last use taken from the media index
+// Index entries whose file is gone are dropped
+func (c *MediaCache) scanObjects() ([]*cachedObject, error) {
+	files, err := os.ReadDir(c.storage.GetObjectDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read media directory: %w", err)
+	}
+
+	bySum := make(map[string]*cachedObject)
+	var objects []*cachedObject
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		name := file.Name()
+		object := &cachedObject{
+			path:     filepath.Join(c.storage.GetObjectDir(), name),
+			sum:      strings.TrimSuffix(name, filepath.Ext(name)),
+			size:     info.Size(),
+			lastUsed: info.ModTime(),
+		}
+		bySum[object.sum] = object
+		objects = append(objects, object)
+	}
+
+	entries, err := c.storage.ListMediaEntries()
+	if err != nil {
+		return nil, err
+	}
+	var orphaned []string
+	for i := range entries {
+		object, ok := bySum[entries[i].SHA256]
+		if !ok {
+			orphaned = append(orphaned, entries[i].URL)
+			continue
+		}
+		object.urls = append(object.urls, entries[i].URL)
+		if lastUsed := entries[i].LastUsed(); lastUsed.After(object.lastUsed) {
+			object.lastUsed = lastUsed
+		}
+	}
+	if err := c.storage.DeleteMediaEntries(orphaned); err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// protectedSums returns the hashes of media used by the given ads
+func (c *MediaCache) protectedSums(inUse []models.Ad, objects []*cachedObject) map[string]bool {
+	urls := make(map[string]bool)
+	protected := make(map[string]bool)
+	for i := range inUse {
+		urls[inUse[i].ContentURL] = true
+		if sum := inUse[i].SHA256(); sum != "" {
+			protected[sum] = true
+		}
+	}
+	for _, object := range objects {
+		for _, url := range object.urls {
+			if urls[url] {
+				protected[object.sum] = true
+			}
+		}
+	}
+	return protected
+}
+
+// removeAbandoned removes old partial downloads and per-ad directories left by older versions
+// Directories are only removed once a playlist is known and after abandonedDirGracePeriod unchanged
+func (c *MediaCache) removeAbandoned(inUse []models.Ad) {
+	partialCutoff := time.Now().Add(-partialMaxAge)
+	temps, _ := os.ReadDir(c.storage.GetTempDir())
+	for _, temp := range temps {
+		if info, err := temp.Info(); err == nil && info.ModTime().Before(partialCutoff) {
+			os.Remove(filepath.Join(c.storage.GetTempDir(), temp.Name()))
+		}
+	}
+
+	if len(inUse) == 0 {
+		return
+	}
+
+	// Ads created by test-ads keep their files in media/<adID>, so only remove gone ads
+	dirCutoff := time.Now().Add(-abandonedDirGracePeriod)
+	ids := make(map[string]bool)
+	for i := range inUse {
+		ids[inUse[i].ID] = true
+	}
+	mediaDir := c.storage.GetMediaDir()
+	dirs, _ := os.ReadDir(mediaDir)
+	for _, dir := range dirs {
+		name := dir.Name()
+		if !dir.IsDir() || ids[name] || name == filepath.Base(c.storage.GetObjectDir()) ||
+			name == filepath.Base(c.storage.GetTempDir()) {
+			continue
+		}
+		if info, err := dir.Info(); err != nil || info.ModTime().After(dirCutoff) {
+			continue
+		}
+		adMediaDir := filepath.Join(mediaDir, name)
+		if err := os.RemoveAll(adMediaDir); err != nil {
+			log.Printf("[%s] [CACHE] [WARN] Failed to remove old media directory %s: %v",
+				time.Now().Format("15:04:05.000"), adMediaDir, err)
+		} else {
+			log.Printf("[%s] [CACHE] Removed old media directory %s", time.Now().Format("15:04:05.000"), adMediaDir)
+		}
+	}
+}
+
+// dirSize returns the total size of the regular files directly in dir
+func dirSize(dir string) int64 {
+	var total int64
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		if info, err := file.Info(); err == nil && info.Mode().IsRegular() {
+			total += info.Size()
+		}
+	}
+	return total
+}
@@ -1,15 +1,20 @@
 package player
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"mnemoCast-client/internal/ads"
 	"mnemoCast-client/internal/models"
+	"mnemoCast-client/internal/retry"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,125 +22,264 @@ import (
 type Downloader struct {
 	storage    *ads.Storage
 	httpClient *http.Client
-	maxRetries int
-	retryDelay time.Duration
+	retryPolicy *retry.Policy
+	mediaLocks sync.Map // Checksum or URL -> *sync.Mutex, so the same content isn't fetched twice at once
+	
+	stallTimeout time.Duration
+	limiter      *rateLimiter // Shared by all downloads
+	progressMu   sync.RWMutex // Guards onProgress, which may be set while downloads run
+	onProgress   func(DownloadProgress)
+	
+	verifyCached bool     // Re-hash cached files before use, so tampered media isn't played
+	verifiedFiles sync.Map // Path -> fileStamp of the last successful check
+}
+
+// fileStamp identifies a version of a file for the verification memo
+type fileStamp struct {
+	size       int64
+	modTime    time.Time
+	inode      uint64
+	changeTime time.Time // Inode change time, which can't be set back like modTime
 }
 
 // NewDownloader creates a new downloader instance
-func NewDownloader(storage *ads.Storage, maxRetries int, retryDelaySeconds int) *Downloader {
+func NewDownloader(storage *ads.Storage, retryPolicy *retry.Policy) *Downloader {
+	if retryPolicy == nil {
+		retryPolicy = retry.DefaultPolicy()
+	}
 	return &Downloader{
 		storage: storage,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		maxRetries: maxRetries,
-		retryDelay: time.Duration(retryDelaySeconds) * time.Second,
+		// No total timeout: large files may take hours on slow links, so stalls are detected instead
+		httpClient:   &http.Client{},
+		retryPolicy:  retryPolicy,
+		stallTimeout: DefaultStallTimeout,
+		limiter:      &rateLimiter{},
 	}
 }
 
+// SetTransport sets the HTTP transport used for media downloads
+func (d *Downloader) SetTransport(transport http.RoundTripper) {
+	d.httpClient = &http.Client{
+		Transport: transport,
+	}
+}
+
+// SetVerifyCached makes cached and local files be checked against the ad's checksum before use
+// Files are re-hashed only when their inode or change time (size and modification time elsewhere) changes
+func (d *Downloader) SetVerifyCached(enabled bool) {
+	d.verifyCached = enabled
+}
+
 // DownloadAdMedia downloads the media file for an ad
 // Returns the local file path if successful
 // Supports both HTTP URLs and file:// URLs for local testing
 func (d *Downloader) DownloadAdMedia(ad *models.Ad) (string, error) {
-	// Check if already cached
-	if localPath, exists := d.GetLocalPath(ad); exists {
-		log.Printf("[%s] [DOWNLOAD] Media already cached: %s", time.Now().Format("15:04:05.000"), localPath)
-		return localPath, nil
-	}
-	
+	return d.DownloadAdMediaContext(context.Background(), ad)
+}
+
+// DownloadAdMediaContext is like DownloadAdMedia but aborts the download and retry waits when ctx is cancelled
+// Media is stored by content hash, so ads with the same creative share one verified file
+func (d *Downloader) DownloadAdMediaContext(ctx context.Context, ad *models.Ad) (string, error) {
 	// Handle file:// URLs (for local testing)
 	if strings.HasPrefix(ad.ContentURL, "file://") {
 		localPath := strings.TrimPrefix(ad.ContentURL, "file://")
 		if _, err := os.Stat(localPath); err == nil {
+			if d.verifyCached && ad.SHA256() != "" && !d.verifyFile(localPath, ad.SHA256()) {
+				return "", fmt.Errorf("local file %s does not match the ad's checksum", localPath)
+			}
 			log.Printf("[%s] [DOWNLOAD] Using local file: %s", time.Now().Format("15:04:05.000"), localPath)
 			return localPath, nil
 		}
 		return "", fmt.Errorf("local file not found: %s", localPath)
 	}
 	
-	// Ensure ad media directory exists
-	if err := d.storage.EnsureAdMediaDir(ad.ID); err != nil {
-		return "", fmt.Errorf("failed to create media directory: %w", err)
-	}
+	unlock := d.lockMedia(ad)
+	defer unlock()
 	
-	// Determine file extension from URL or ad type
-	ext := d.getFileExtension(ad.ContentURL, ad.Type)
-	fileName := fmt.Sprintf("%s%s", ad.ID, ext)
-	localPath := d.storage.GetAdMediaPath(ad.ID, fileName)
+	// Check if already cached
+	if localPath, exists := d.GetLocalPath(ad); exists {
+		log.Printf("[%s] [DOWNLOAD] Media already cached: %s", time.Now().Format("15:04:05.000"), localPath)
+		return localPath, nil
+	}
 	
 	// Download file
-	log.Printf("[%s] [DOWNLOAD] Downloading media: %s -> %s", 
-		time.Now().Format("15:04:05.000"), ad.ContentURL, localPath)
-	
-	var lastErr error
-	for attempt := 0; attempt <= d.maxRetries; attempt++ {
-		if attempt > 0 {
-			delay := d.retryDelay * time.Duration(attempt)
-			log.Printf("[%s] [DOWNLOAD] Retrying download (attempt %d/%d) after %v...", 
-				time.Now().Format("15:04:05.000"), attempt, d.maxRetries, delay)
-			time.Sleep(delay)
+	log.Printf("[%s] [DOWNLOAD] Downloading media: %s", time.Now().Format("15:04:05.000"), ad.ContentURL)
+	
+	var localPath string
+	policy := d.retryPolicy
+	err := policy.Do(ctx, func(attempt int) error {
+		path, err := d.downloadObject(ctx, ad)
+		if err != nil {
+			log.Printf("[%s] [DOWNLOAD] Download attempt %d/%d failed: %v", 
+				time.Now().Format("15:04:05.000"), attempt+1, policy.MaxRetries+1, err)
+			return err
 		}
-		
-		err := d.DownloadFile(ad.ContentURL, localPath)
-		if err == nil {
-			// Verify file was downloaded successfully
-			if info, err := os.Stat(localPath); err == nil && info.Size() > 0 {
-				log.Printf("[%s] [DOWNLOAD] Media downloaded successfully: %s (%d bytes)", 
-					time.Now().Format("15:04:05.000"), localPath, info.Size())
-				return localPath, nil
-			}
-			err = fmt.Errorf("downloaded file is empty or invalid")
+		localPath = path
+		return nil
+	}, func(retryNum int, delay time.Duration, err error) {
+		log.Printf("[%s] [DOWNLOAD] Retrying download (attempt %d/%d) after %v...", 
+			time.Now().Format("15:04:05.000"), retryNum, policy.MaxRetries, delay)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download media: %w", err)
+	}
+
+	return localPath, nil
+}
+
+// downloadObject downloads an ad's content, verifies it and moves it into the object store
+func (d *Downloader) downloadObject(ctx context.Context, ad *models.Ad) (string, error) {
+	tmpPath, sum, size, err := d.downloadPartial(ctx, ad.ContentURL)
+	if err != nil {
+		return "", err
+	}
+	// A complete download that fails verification must not be resumed
+	defer d.discardPartial(tmpPath)
+	
+	if size == 0 {
+		return "", fmt.Errorf("downloaded file is empty")
+	}
+	if ad.Size > 0 && size != ad.Size {
+		return "", fmt.Errorf("size mismatch: expected %d bytes, got %d", ad.Size, size)
+	}
+	if expected := ad.SHA256(); expected != "" && sum != expected {
+		return "", fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", expected, sum)
+	}
+	
+	// Another ad may already have brought in the same content
+	localPath, exists := d.findObject(sum)
+	if !exists {
+		if err := os.MkdirAll(d.storage.GetObjectDir(), 0755); err != nil {
+			return "", fmt.Errorf("failed to create media directory: %w", err)
+		}
+		localPath = filepath.Join(d.storage.GetObjectDir(), sum+d.getFileExtension(ad.ContentURL, ad.Type))
+		if err := os.Rename(tmpPath, localPath); err != nil {
+			return "", fmt.Errorf("failed to move downloaded file into place: %w", err)
 		}
-		
-		lastErr = err
-		log.Printf("[%s] [DOWNLOAD] Download attempt %d/%d failed: %v", 
-			time.Now().Format("15:04:05.000"), attempt+1, d.maxRetries+1, err)
 	}
 	
-	return "", fmt.Errorf("failed to download media after %d attempts: %w", d.maxRetries+1, lastErr)
+	entry := &ads.MediaEntry{
+		URL:       ad.ContentURL,
+		SHA256:    sum,
+		Size:      size,
+		File:      filepath.Base(localPath),
+		FetchedAt: time.Now(),
+	}
+	if err := d.storage.PutMediaEntry(entry); err != nil {
+		log.Printf("[%s] [DOWNLOAD] [WARN] %v", time.Now().Format("15:04:05.000"), err)
+	}
+	
+	log.Printf("[%s] [DOWNLOAD] Media downloaded successfully: %s (%d bytes, sha256 %s)", 
+		time.Now().Format("15:04:05.000"), localPath, size, sum[:12])
+	return localPath, nil
 }
 
 // GetLocalPath returns the local path for an ad's media file if it exists
+// Ads with a checksum match any stored object with that hash; others are looked up by URL
 func (d *Downloader) GetLocalPath(ad *models.Ad) (string, bool) {
-	ext := d.getFileExtension(ad.ContentURL, ad.Type)
-	fileName := fmt.Sprintf("%s%s", ad.ID, ext)
-	localPath := d.storage.GetAdMediaPath(ad.ID, fileName)
+	if expected := ad.SHA256(); expected != "" {
+		localPath, exists := d.findObject(expected)
+		if !exists {
+			return "", false
+		}
+		if ad.Size > 0 {
+			if info, err := os.Stat(localPath); err != nil || info.Size() != ad.Size {
+				return "", false
+			}
+		}
+		if d.verifyCached && !d.verifyFile(localPath, expected) {
+			// Tampered or corrupted; remove it so the next download replaces it
+			log.Printf("[%s] [DOWNLOAD] [WARN] Cached media %s does not match its checksum, removing it", 
+				time.Now().Format("15:04:05.000"), localPath)
+			os.Remove(localPath)
+			return "", false
+		}
+		return localPath, true
+	}
 	
-	if info, err := os.Stat(localPath); err == nil && info.Size() > 0 {
+	entry, err := d.storage.GetMediaEntry(ad.ContentURL)
+	if err != nil {
+		return "", false
+	}
+	localPath := filepath.Join(d.storage.GetObjectDir(), entry.File)
+	if info, err := os.Stat(localPath); err == nil && info.Size() == entry.Size {
 		return localPath, true
 	}
 	
 	return "", false
 }
 
+// verifyFile reports whether a file's SHA-256 is sum, reusing the result while the file is unchanged
+// The memo only lives in memory, so every file is hashed again before its first use after startup
+func (d *Downloader) verifyFile(path, sum string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	stamp := statFile(info)
+	if previous, ok := d.verifiedFiles.Load(path); ok && previous.(fileStamp) == stamp {
+		return true
+	}
+	
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return false
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != sum {
+		d.verifiedFiles.Delete(path)
+		return false
+	}
+	d.verifiedFiles.Store(path, stamp)
+	return true
+}
+
 // IsCached checks if an ad's media is already cached
 func (d *Downloader) IsCached(ad *models.Ad) bool {
 	_, exists := d.GetLocalPath(ad)
 	return exists
 }
 
+// findObject returns the stored object with the given SHA-256, whatever its extension
+func (d *Downloader) findObject(sum string) (string, bool) {
+	if !isHexSum(sum) {
+		return "", false
+	}
+	matches, _ := filepath.Glob(filepath.Join(d.storage.GetObjectDir(), sum+"*"))
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() && info.Size() > 0 {
+			return match, true
+		}
+	}
+	return "", false
+}
+
+// lockMedia serialises downloads of the same content; call the returned function to unlock
+func (d *Downloader) lockMedia(ad *models.Ad) func() {
+	lock, _ := d.mediaLocks.LoadOrStore(mediaKey(ad), &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
 // DownloadFile downloads a file from a URL to a local path
 func (d *Downloader) DownloadFile(url, destPath string) error {
-	// Create HTTP request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	
-	// Set user agent
-	req.Header.Set("User-Agent", "MnemoCast-Client/1.0")
-	
-	// Execute request
-	resp, err := d.httpClient.Do(req)
+	return d.DownloadFileContext(context.Background(), url, destPath)
+}
+
+// DownloadFileContext is like DownloadFile but aborts the transfer when ctx is cancelled
+// Statuses that won't change on retry (e.g. 404) are reported as permanent errors;
+// an interrupted transfer resumes where it stopped on the next call
+func (d *Downloader) DownloadFileContext(ctx context.Context, url, destPath string) error {
+	tmpPath, _, _, err := d.downloadPartial(ctx, url)
 	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return err
 	}
+	defer d.discardPartial(tmpPath)
 	
 	// Create destination file
 	destDir := filepath.Dir(destPath)
@@ -143,16 +287,8 @@ func (d *Downloader) DownloadFile(url, destPath string) error {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 	
-	file, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
-	}
-	defer file.Close()
-	
-	// Copy response body to file
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		return fmt.Errorf("failed to move downloaded file into place: %w", err)
 	}
 	
 	return nil
@@ -184,45 +320,19 @@ func (d *Downloader) getFileExtension(url, adType string) string {
 	}
 }
 
-// CleanupOldMedia removes media files for ads that are no longer in the playlist
-func (d *Downloader) CleanupOldMedia(currentAdIDs map[string]bool) error {
-	mediaDir := d.storage.GetMediaDir()
-	
-	// Read all directories in media folder
-	entries, err := os.ReadDir(mediaDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // Media directory doesn't exist, nothing to clean
-		}
-		return fmt.Errorf("failed to read media directory: %w", err)
-	}
-	
-	cleaned := 0
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		
-		adID := entry.Name()
-		if !currentAdIDs[adID] {
-			// Ad no longer exists, remove its media directory
-			adMediaDir := filepath.Join(mediaDir, adID)
-			if err := os.RemoveAll(adMediaDir); err != nil {
-				log.Printf("[%s] [DOWNLOAD] Failed to remove old media directory %s: %v", 
-					time.Now().Format("15:04:05.000"), adMediaDir, err)
-			} else {
-				cleaned++
-				log.Printf("[%s] [DOWNLOAD] Cleaned up old media: %s", 
-					time.Now().Format("15:04:05.000"), adMediaDir)
-			}
-		}
+// mediaKey identifies an ad's content: its checksum if known, otherwise its URL
+func mediaKey(ad *models.Ad) string {
+	if sum := ad.SHA256(); sum != "" {
+		return sum
 	}
-	
-	if cleaned > 0 {
-		log.Printf("[%s] [DOWNLOAD] Cleaned up %d old media directories", 
-			time.Now().Format("15:04:05.000"), cleaned)
-	}
-	
-	return nil
+	return ad.ContentURL
 }
 
+// isHexSum reports whether s looks like a hex SHA-256
+func isHexSum(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
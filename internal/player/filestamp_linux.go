@@ -0,0 +1,19 @@
+package player
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statFile returns the stamp of a file's current version
+// The inode changes when a file is replaced and the ctime on any write or metadata change,
+// and neither can be set back by hand the way the modification time can
+func statFile(info os.FileInfo) fileStamp {
+	stamp := fileStamp{size: info.Size(), modTime: info.ModTime()}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		stamp.inode = stat.Ino
+		stamp.changeTime = time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+	}
+	return stamp
+}
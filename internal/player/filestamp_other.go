@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package player
+
+import "os"
+
+// statFile returns the stamp of a file's current version
+// Without an inode and change time only the size and modification time are available
+func statFile(info os.FileInfo) fileStamp {
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}
+}
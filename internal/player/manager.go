@@ -0,0 +1,343 @@
+package player
+
+import (
+	"context"
+	"log"
+	"mnemoCast-client/internal/models"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Download pool settings used when the config leaves them unset
+const (
+	DefaultDownloadWorkers  = 3
+	DefaultDownloadsPerHost = 2
+)
+
+// failedHistorySize is how many failed downloads the status keeps
+const failedHistorySize = 20
+
+// DownloadInfo describes one download in the status
+type DownloadInfo struct {
+	AdID       string
+	URL        string
+	Host       string
+	Due        time.Time // When the ad is expected to play; earlier is fetched first
+	Downloaded int64
+	Total      int64
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      string
+}
+
+// DownloadStatus lists queued, running and recently failed downloads
+type DownloadStatus struct {
+	Queued     []DownloadInfo
+	InProgress []DownloadInfo
+	Failed     []DownloadInfo // Newest last
+	Completed  int            // Downloads finished since start
+}
+
+// downloadResult is delivered to everyone waiting on a job
+type downloadResult struct {
+	path string
+	err  error
+}
+
+// downloadJob is one piece of media to fetch, shared by all requests for the same content
+type downloadJob struct {
+	key     string
+	ad      models.Ad
+	host    string
+	due     time.Time
+	seq     uint64 // Submission order, breaks ties between equal due times
+	pinned  bool   // Requested with Enqueue; kept even if no one waits for it
+	waiters []chan downloadResult
+	info    DownloadInfo
+}
+
+// DownloadManager fetches media on a pool of workers
+// Jobs run soonest-due first, each host gets a limited number of connections, and
+// concurrent requests for the same content share one download.
+type DownloadManager struct {
+	downloader *Downloader
+	workers    int
+	perHost    int
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queued    []*downloadJob
+	active    map[string]*downloadJob // By job key
+	byURL     map[string]*downloadJob // Active jobs by URL, for progress reports
+	hostLoad  map[string]int
+	failed    []DownloadInfo
+	completed int
+	nextSeq   uint64
+	stopped   bool
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewDownloadManager creates a manager; values <= 0 use the defaults
+func NewDownloadManager(downloader *Downloader, workers, perHost int) *DownloadManager {
+	if workers <= 0 {
+		workers = DefaultDownloadWorkers
+	}
+	if perHost <= 0 {
+		perHost = DefaultDownloadsPerHost
+	}
+	m := &DownloadManager{
+		downloader: downloader,
+		workers:    workers,
+		perHost:    perHost,
+		active:     make(map[string]*downloadJob),
+		byURL:      make(map[string]*downloadJob),
+		hostLoad:   make(map[string]int),
+	}
+	m.cond = sync.NewCond(&m.mu)
+	downloader.SetOnProgress(m.onProgress)
+	return m
+}
+
+// Start runs the workers until ctx is cancelled; later calls do nothing
+func (m *DownloadManager) Start(ctx context.Context) {
+	m.startOnce.Do(func() {
+		for i := 0; i < m.workers; i++ {
+			m.wg.Add(1)
+			go m.worker(ctx)
+		}
+
+		go func() {
+			<-ctx.Done()
+			m.mu.Lock()
+			m.stopped = true
+			m.mu.Unlock()
+			m.cond.Broadcast()
+		}()
+	})
+}
+
+// Wait blocks until all workers have exited
+func (m *DownloadManager) Wait() {
+	m.wg.Wait()
+}
+
+// Enqueue schedules an ad's media for download without waiting for it
+func (m *DownloadManager) Enqueue(ad models.Ad, due time.Time) {
+	if _, ok := m.downloader.GetLocalPath(&ad); ok {
+		return
+	}
+	m.submit(ad, due, nil, true)
+}
+
+// Fetch downloads an ad's media and waits for it, returning the local path
+// If ctx ends first and nobody else needs the job, a job that hasn't started is dropped
+func (m *DownloadManager) Fetch(ctx context.Context, ad models.Ad, due time.Time) (string, error) {
+	if path, ok := m.downloader.GetLocalPath(&ad); ok {
+		return path, nil
+	}
+
+	done := make(chan downloadResult, 1)
+	job := m.submit(ad, due, done, false)
+
+	select {
+	case result := <-done:
+		return result.path, result.err
+	case <-ctx.Done():
+		m.abandon(job, done)
+		return "", ctx.Err()
+	}
+}
+
+// Status returns a snapshot of the download queue, queued jobs in the order they will run
+func (m *DownloadManager) Status() DownloadStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := DownloadStatus{Completed: m.completed}
+	for _, job := range m.queued {
+		status.Queued = append(status.Queued, job.info)
+	}
+	for _, job := range m.active {
+		status.InProgress = append(status.InProgress, job.info)
+	}
+	sort.Slice(status.Queued, func(i, j int) bool {
+		return status.Queued[i].Due.Before(status.Queued[j].Due)
+	})
+	status.Failed = append(status.Failed, m.failed...)
+	return status
+}
+
+// submit adds a request to the job for its content, creating the job if needed
+func (m *DownloadManager) submit(ad models.Ad, due time.Time, done chan downloadResult, pinned bool) *downloadJob {
+	key := mediaKey(&ad)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.active[key]
+	if job == nil {
+		for _, queued := range m.queued {
+			if queued.key == key {
+				job = queued
+				break
+			}
+		}
+	}
+
+	if job == nil {
+		m.nextSeq++
+		job = &downloadJob{
+			key:  key,
+			ad:   ad,
+			host: hostOf(ad.ContentURL),
+			due:  due,
+			seq:  m.nextSeq,
+		}
+		job.info = DownloadInfo{AdID: ad.ID, URL: ad.ContentURL, Host: job.host, Due: due}
+		m.queued = append(m.queued, job)
+		m.cond.Signal()
+	} else if due.Before(job.due) {
+		// A more urgent request moves the shared job up
+		job.due = due
+		job.info.Due = due
+	}
+
+	job.pinned = job.pinned || pinned
+	if done != nil {
+		job.waiters = append(job.waiters, done)
+	}
+	return job
+}
+
+// abandon removes a waiter that stopped waiting, dropping the job if it was its last reason to run
+func (m *DownloadManager) abandon(job *downloadJob, done chan downloadResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, waiter := range job.waiters {
+		if waiter == done {
+			job.waiters = append(job.waiters[:i], job.waiters[i+1:]...)
+			break
+		}
+	}
+	if len(job.waiters) > 0 || job.pinned {
+		return
+	}
+	for i, queued := range m.queued {
+		if queued == job {
+			m.queued = append(m.queued[:i], m.queued[i+1:]...)
+			return
+		}
+	}
+}
+
+// worker runs jobs until the manager stops
+func (m *DownloadManager) worker(ctx context.Context) {
+	defer m.wg.Done()
+
+	for {
+		job := m.next()
+		if job == nil {
+			return
+		}
+
+		path, err := m.downloader.DownloadAdMediaContext(ctx, &job.ad)
+		m.finish(job, path, err)
+	}
+}
+
+// next blocks until a job can run: the soonest-due queued job whose host has a free slot
+// Returns nil once the manager has stopped
+func (m *DownloadManager) next() *downloadJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		if m.stopped {
+			return nil
+		}
+
+		best := -1
+		for i, job := range m.queued {
+			if job.host != "" && m.hostLoad[job.host] >= m.perHost {
+				continue
+			}
+			if best < 0 || job.due.Before(m.queued[best].due) ||
+				(job.due.Equal(m.queued[best].due) && job.seq < m.queued[best].seq) {
+				best = i
+			}
+		}
+
+		if best >= 0 {
+			job := m.queued[best]
+			m.queued = append(m.queued[:best], m.queued[best+1:]...)
+			m.active[job.key] = job
+			m.byURL[job.ad.ContentURL] = job
+			m.hostLoad[job.host]++
+			job.info.StartedAt = time.Now()
+			return job
+		}
+
+		m.cond.Wait()
+	}
+}
+
+// finish records a job's outcome and hands the result to its waiters
+func (m *DownloadManager) finish(job *downloadJob, path string, err error) {
+	m.mu.Lock()
+	delete(m.active, job.key)
+	if m.byURL[job.ad.ContentURL] == job {
+		delete(m.byURL, job.ad.ContentURL)
+	}
+	m.hostLoad[job.host]--
+	if m.hostLoad[job.host] <= 0 {
+		delete(m.hostLoad, job.host)
+	}
+
+	job.info.FinishedAt = time.Now()
+	if err != nil {
+		job.info.Error = err.Error()
+		m.failed = append(m.failed, job.info)
+		if len(m.failed) > failedHistorySize {
+			m.failed = m.failed[len(m.failed)-failedHistorySize:]
+		}
+		log.Printf("[%s] [DOWNLOAD] [WARN] Failed to fetch media for ad %s: %v",
+			time.Now().Format("15:04:05.000"), job.ad.ID, err)
+	} else {
+		m.completed++
+	}
+
+	waiters := job.waiters
+	job.waiters = nil
+	m.mu.Unlock()
+
+	// A host slot is free again
+	m.cond.Broadcast()
+
+	for _, waiter := range waiters {
+		waiter <- downloadResult{path: path, err: err}
+	}
+}
+
+// onProgress updates the status of the job a progress report belongs to
+func (m *DownloadManager) onProgress(progress DownloadProgress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job := m.byURL[progress.URL]; job != nil {
+		job.info.Downloaded = progress.Downloaded
+		job.info.Total = progress.Total
+	}
+}
+
+// hostOf returns the host of a URL, or "" for local files and unparsable URLs
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
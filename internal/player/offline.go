@@ -0,0 +1,144 @@
+package player
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"log"
+	"mnemoCast-client/internal/ads"
+	"mnemoCast-client/internal/models"
+	"os"
+	"time"
+)
+
+// OfflineStatus describes how the offline policy affects the playlist
+type OfflineStatus struct {
+	LastFetch     time.Time     // Last time the server confirmed the playlist; zero if never
+	OfflineFor    time.Duration // Time since LastFetch; 0 if the policy is disabled
+	ExpiredAds    int           // Active ads dropped because the playlist wasn't confirmed in time
+	UsingFallback bool          // The fallback playlist is playing instead
+}
+
+// SetOfflinePolicy drops ads once the playlist hasn't been confirmed by the server for too long
+// An ad's own maxOfflineAge overrides maxAge; maxAge 0 leaves ads without one unlimited.
+// lastFetch reports when the server last confirmed the playlist.
+func (p *Playlist) SetOfflinePolicy(maxAge time.Duration, lastFetch func() time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxOfflineAge = maxAge
+	p.lastFetch = lastFetch
+}
+
+// SetFallback sets the ads played when the offline policy leaves nothing else to play
+func (p *Playlist) SetFallback(ads []models.Ad) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fallback = ads
+}
+
+// GetFallback returns a copy of the fallback ads
+func (p *Playlist) GetFallback() []models.Ad {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	fallback := make([]models.Ad, len(p.fallback))
+	copy(fallback, p.fallback)
+	return fallback
+}
+
+// GetOfflineStatus returns the offline state as of the last playlist evaluation
+func (p *Playlist) GetOfflineStatus() OfflineStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	status := p.offline
+	if p.lastFetch != nil && !status.LastFetch.IsZero() {
+		status.OfflineFor = time.Since(status.LastFetch)
+	}
+	return status
+}
+
+// playableLocked returns the ads to play now and updates the offline status
+// Callers must hold p.mu for writing
+func (p *Playlist) playableLocked(now time.Time) []models.Ad {
+	active := p.filterByTimeLocked(now)
+	if p.lastFetch == nil {
+		return active
+	}
+
+	lastFetch := p.lastFetch()
+	var playable []models.Ad
+	expired := 0
+	for _, ad := range active {
+		if p.offlineExpired(&ad, lastFetch, now) {
+			expired++
+			continue
+		}
+		playable = append(playable, ad)
+	}
+
+	usingFallback := false
+	if len(playable) == 0 && expired > 0 && len(p.fallback) > 0 {
+		playable = filterByTime(p.fallback, now)
+		usingFallback = len(playable) > 0
+	}
+
+	if expired != p.offline.ExpiredAds || usingFallback != p.offline.UsingFallback {
+		p.logOfflineChange(lastFetch, expired, usingFallback)
+	}
+	p.offline = OfflineStatus{
+		LastFetch:     lastFetch,
+		ExpiredAds:    expired,
+		UsingFallback: usingFallback,
+	}
+	return playable
+}
+
+// offlineExpired reports whether an ad may no longer run because the playlist is too old
+// A playlist that was never confirmed counts as expired for every ad with a limit
+func (p *Playlist) offlineExpired(ad *models.Ad, lastFetch, now time.Time) bool {
+	maxAge := p.maxOfflineAge
+	if ad.MaxOfflineAge > 0 {
+		maxAge = time.Duration(ad.MaxOfflineAge) * time.Second
+	}
+	if maxAge <= 0 {
+		return false
+	}
+	return lastFetch.IsZero() || now.Sub(lastFetch) > maxAge
+}
+
+// logOfflineChange logs when ads start or stop being dropped by the offline policy
+func (p *Playlist) logOfflineChange(lastFetch time.Time, expired int, usingFallback bool) {
+	timestamp := time.Now().Format("15:04:05.000")
+	since := "never"
+	if !lastFetch.IsZero() {
+		since = lastFetch.Format(time.RFC3339)
+	}
+
+	switch {
+	case usingFallback:
+		log.Printf("[%s] [PLAYER] [OFFLINE] All %d active ads exceeded their offline age (last confirmed: %s), playing fallback playlist",
+			timestamp, expired, since)
+	case expired > 0:
+		log.Printf("[%s] [PLAYER] [OFFLINE] %d ads exceeded their offline age (last confirmed: %s) and were dropped",
+			timestamp, expired, since)
+	default:
+		log.Printf("[%s] [PLAYER] [OFFLINE] Playlist confirmed, no ads are dropped for offline age", timestamp)
+	}
+}
+
+// LoadFallbackPlaylist reads a fallback playlist in the ad delivery response format
+// With a pinned key the file must be a signed envelope, as local files are no more trusted than the network
+func LoadFallbackPlaylist(path string, key ed25519.PublicKey) ([]models.Ad, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fallback playlist: %w", err)
+	}
+
+	fallback, err := ads.ParsePlaylist(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("fallback playlist %s rejected: %w", path, err)
+	}
+	for _, rejection := range ads.ValidatePlaylist(fallback).Rejected {
+		log.Printf("[%s] [PLAYER] [WARN] Fallback ad %q (#%d) rejected: %s",
+			time.Now().Format("15:04:05.000"), rejection.AdID, rejection.Index+1, rejection.Reason)
+	}
+	return fallback.Ads, nil
+}
@@ -2,9 +2,15 @@ package player
 
 import (
 	"context"
+	"crypto/ed25519"
 	"log"
 	"mnemoCast-client/internal/ads"
+	"mnemoCast-client/internal/events"
 	"mnemoCast-client/internal/models"
+	"mnemoCast-client/internal/retry"
+	"mnemoCast-client/pkg/signing"
+	"net/http"
+	"path/filepath"
 	"sync"
 	"time"
 )
@@ -28,13 +34,30 @@ type PlayerStats struct {
 	PlaybackStartTime time.Time
 	LastError         error
 	State             PlayerState
+	
+	// Playlist being prefetched while the current one keeps playing
+	PendingPlaylistID string
+	PrefetchReady     int
+	PrefetchTotal     int
+	PrefetchStarted   time.Time
+	
+	// Offline policy state (see offline.go)
+	LastFetch         time.Time
+	OfflineFor        time.Duration
+	OfflineExpiredAds int
+	UsingFallback     bool
 }
 
+// mediaWaitTimeout is how long playback waits for an ad's media before moving on
+const mediaWaitTimeout = 5 * time.Second
+
 // Player orchestrates ad playback
 type Player struct {
 	playlist   *Playlist
 	scheduler  *Scheduler
 	downloader *Downloader
+	downloads  *DownloadManager
+	cache      *MediaCache
 	renderer   *RendererManager
 	storage    *ads.Storage
 	config     *models.ScreenConfig
@@ -42,6 +65,17 @@ type Player struct {
 	currentAd  *models.Ad
 	state      PlayerState
 	stats      PlayerStats
+
+	// Proof-of-play tracking for the ad currently on screen
+	eventQueue   *events.Queue
+	playStart    time.Time
+	playRenderer string
+	playlistID   string
+	
+	// Staging of the next playlist (see prefetch.go)
+	prefetchSeq    uint64
+	prefetchCancel context.CancelFunc
+	pendingAds     []models.Ad
 	
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -59,22 +93,57 @@ func NewPlayer(storage *ads.Storage, config *models.ScreenConfig) *Player {
 	// Default scheduler: 30 seconds default duration, 1 second transition delay
 	scheduler := NewScheduler(30, 1)
 	
-	// Create downloader with retry settings from config
-	maxRetries := 3
-	retryDelay := 5
+	// Create downloader with the shared retry policy from config
+	downloader := NewDownloader(storage, retry.FromConfig(config))
+	if config != nil {
+		downloader.SetStallTimeout(time.Duration(config.DownloadStallTimeout) * time.Second)
+		downloader.SetBandwidthLimit(config.DownloadBandwidthLimit)
+		// With signed playlists, cached media is only trusted if it still matches the signed checksum
+		downloader.SetVerifyCached(config.PlaylistPublicKey != "")
+	}
+	
+	var cacheQuota, minFreeDisk int64
+	var workers, perHost int
 	if config != nil {
-		maxRetries = config.RetryAttempts
-		retryDelay = config.RetryDelay
+		cacheQuota, minFreeDisk = config.MediaCacheQuota, config.MinFreeDiskSpace
+		workers, perHost = config.DownloadWorkers, config.DownloadsPerHost
 	}
-	downloader := NewDownloader(storage, maxRetries, retryDelay)
+	downloads := NewDownloadManager(downloader, workers, perHost)
+	cache := NewMediaCache(storage, cacheQuota, minFreeDisk)
 	
 	// Create renderer manager
 	renderer := NewRendererManager()
 	
+	playlist := NewPlaylist()
+	if config != nil && config.FallbackPlaylist != "" {
+		path := config.FallbackPlaylist
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(storage.GetAdsDir(), path)
+		}
+		// A pinned playlist key applies to the fallback too; an unparsable key refuses it
+		var key ed25519.PublicKey
+		var err error
+		if config.PlaylistPublicKey != "" {
+			key, err = signing.ParsePublicKey(config.PlaylistPublicKey)
+		}
+		var fallback []models.Ad
+		if err == nil {
+			fallback, err = LoadFallbackPlaylist(path, key)
+		}
+		if err != nil {
+			log.Printf("[%s] [PLAYER] [WARN] Fallback playlist unavailable: %v", time.Now().Format("15:04:05.000"), err)
+		} else {
+			playlist.SetFallback(fallback)
+			log.Printf("[%s] [PLAYER] Loaded %d fallback ads from %s", time.Now().Format("15:04:05.000"), len(fallback), path)
+		}
+	}
+	
 	return &Player{
-		playlist:   NewPlaylist(),
+		playlist:   playlist,
 		scheduler:  scheduler,
 		downloader: downloader,
+		downloads:  downloads,
+		cache:      cache,
 		renderer:   renderer,
 		storage:    storage,
 		config:     config,
@@ -105,10 +174,22 @@ func (p *Player) Start() error {
 	p.state = PlayerStatePlaying
 	p.stats.State = PlayerStatePlaying
 	
-	// Start playback loop
+	// Start download workers and playback loop
+	p.downloads.Start(p.ctx)
+	// Fallback media is fetched after everything the playlist needs soon
+	for _, ad := range p.playlist.GetFallback() {
+		p.downloads.Enqueue(ad, time.Now().Add(time.Hour))
+	}
 	p.wg.Add(1)
 	go p.playbackLoop()
 	
+	// Bring the media cache within its limits
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.enforceCache()
+	}()
+	
 	log.Printf("[%s] [PLAYER] Player started", time.Now().Format("15:04:05.000"))
 	return nil
 }
@@ -130,12 +211,14 @@ func (p *Player) Stop() error {
 	}
 	
 	p.cancel()
+	record := p.finishCurrentAdLocked(models.PlayOutcomeInterrupted)
 	p.state = PlayerStateStopped
 	p.stats.State = PlayerStateStopped
-	p.currentAd = nil
 	
 	p.mu.Unlock()
+	p.enqueueRecord(record)
 	p.wg.Wait()
+	p.downloads.Wait()
 	p.mu.Lock()
 	
 	log.Printf("[%s] [PLAYER] Player stopped", time.Now().Format("15:04:05.000"))
@@ -143,16 +226,21 @@ func (p *Player) Stop() error {
 }
 
 // Pause pauses the player
+// The ad on screen is recorded as interrupted and playback resumes with the next ad,
+// so paused time never counts towards an ad's duration
 func (p *Player) Pause() error {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-	
 	if p.state != PlayerStatePlaying {
+		p.mu.Unlock()
 		return nil
 	}
 	
+	record := p.finishCurrentAdLocked(models.PlayOutcomeInterrupted)
 	p.state = PlayerStatePaused
 	p.stats.State = PlayerStatePaused
+	p.mu.Unlock()
+	
+	p.enqueueRecord(record)
 	log.Printf("[%s] [PLAYER] Player paused", time.Now().Format("15:04:05.000"))
 	return nil
 }
@@ -182,18 +270,50 @@ func (p *Player) GetState() PlayerState {
 // GetStats returns player statistics
 func (p *Player) GetStats() PlayerStats {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
-	return p.stats
+	stats := p.stats
+	p.mu.RUnlock()
+	
+	offline := p.playlist.GetOfflineStatus()
+	stats.LastFetch = offline.LastFetch
+	stats.OfflineFor = offline.OfflineFor
+	stats.OfflineExpiredAds = offline.ExpiredAds
+	stats.UsingFallback = offline.UsingFallback
+	return stats
 }
 
-// UpdateAds updates the playlist with new ads
+// SetLastFetch enables the offline policy, using lastFetch to learn when the server last confirmed the playlist
+// Ads are dropped once that is longer ago than their maxOfflineAge or the configured maxOfflineAge
+func (p *Player) SetLastFetch(lastFetch func() time.Time) {
+	var maxAge time.Duration
+	if p.config != nil {
+		maxAge = time.Duration(p.config.MaxOfflineAge) * time.Second
+	}
+	p.playlist.SetOfflinePolicy(maxAge, lastFetch)
+}
+
+// UpdateAds stages a new playlist
+// Its media is prefetched in the background and the current playlist keeps playing until
+// enough of it is cached; only media of added, removed or changed ads is touched
 func (p *Player) UpdateAds(adResponse *models.AdDeliveryResponse) {
-	p.mu.Lock()
-	p.playlist.UpdateAds(adResponse)
-	p.mu.Unlock()
-	
-	log.Printf("[%s] [PLAYER] Playlist updated: %d total ads, %d active ads", 
-		time.Now().Format("15:04:05.000"), p.playlist.GetCount(), p.playlist.GetActiveCount())
+	p.stagePlaylist(adResponse)
+}
+
+// activatePlaylistLocked swaps in a new playlist and tidies the media cache in the background
+// Callers must hold p.mu and call announcePlaylist after unlocking. Once Stop has cancelled
+// p.ctx no goroutine is started, so none can be added while Stop waits for them.
+func (p *Player) activatePlaylistLocked(adResponse *models.AdDeliveryResponse) *ads.PlaylistDiff {
+	diff := p.playlist.UpdateAds(adResponse)
+	if !diff.IsEmpty() && p.ctx.Err() == nil {
+		p.wg.Add(1)
+		go p.syncMedia(diff)
+	}
+	return diff
+}
+
+// announcePlaylist logs a playlist swap and calls the update callback
+func (p *Player) announcePlaylist(adResponse *models.AdDeliveryResponse, diff *ads.PlaylistDiff) {
+	log.Printf("[%s] [PLAYER] Playlist updated (%s): %d total ads, %d active ads", 
+		time.Now().Format("15:04:05.000"), diff, p.playlist.GetCount(), p.playlist.GetActiveCount())
 	
 	// Call callback if set
 	if p.onAdsUpdated != nil {
@@ -201,6 +321,47 @@ func (p *Player) UpdateAds(adResponse *models.AdDeliveryResponse) {
 	}
 }
 
+// syncMedia fetches any media still missing and then enforces the cache limits
+func (p *Player) syncMedia(diff *ads.PlaylistDiff) {
+	defer p.wg.Done()
+	
+	// Normally already cached by the prefetch; covers playlists activated without one
+	needed := diff.NeedsMedia()
+	due := p.estimateDue(needed)
+	for _, ad := range needed {
+		p.downloads.Enqueue(ad, due[ad.ID])
+	}
+	
+	p.enforceCache()
+}
+
+// enforceCache evicts media until the cache fits its quota and free-disk limits
+func (p *Player) enforceCache() {
+	if err := p.cache.Enforce(p.mediaInUse()); err != nil {
+		log.Printf("[%s] [PLAYER] [WARN] Media cache cleanup failed: %v", time.Now().Format("15:04:05.000"), err)
+	}
+}
+
+// mediaInUse returns the ads of the current playlist and of any playlist being prefetched
+func (p *Player) mediaInUse() []models.Ad {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	inUse := append(p.playlist.GetAds(), p.pendingAds...)
+	return append(inUse, p.playlist.GetFallback()...)
+}
+
+// SetTransport sets the HTTP transport used to download ad media
+func (p *Player) SetTransport(transport http.RoundTripper) {
+	p.downloader.SetTransport(transport)
+}
+
+// SetEventQueue sets the durable queue that receives proof-of-play records
+func (p *Player) SetEventQueue(queue *events.Queue) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.eventQueue = queue
+}
+
 // SetOnAdsUpdated sets a callback for when ads are updated
 func (p *Player) SetOnAdsUpdated(callback func(*models.AdDeliveryResponse)) {
 	p.mu.Lock()
@@ -224,8 +385,10 @@ func (p *Player) playbackLoop() {
 			log.Printf("[%s] [PLAYER] Playback loop stopping...", time.Now().Format("15:04:05.000"))
 			return
 		case <-ticker.C:
+			// Pause and Stop clear the current ad from other goroutines, so the loop works on a copy
 			p.mu.Lock()
 			state := p.state
+			currentAd := p.currentAd
 			p.mu.Unlock()
 			
 			if state != PlayerStatePlaying {
@@ -233,20 +396,19 @@ func (p *Player) playbackLoop() {
 			}
 			
 			// Check if we need to load a new ad
-			if p.currentAd == nil || p.scheduler.ShouldTransition(p.currentAd, currentAdStartTime) {
-				p.loadNextAd()
-				if p.currentAd != nil {
+			if currentAd == nil || p.scheduler.ShouldTransition(currentAd, currentAdStartTime) {
+				if ad := p.loadNextAd(); ad != nil {
 					currentAdStartTime = time.Now()
-					currentAdDuration = p.scheduler.GetAdDuration(p.currentAd)
+					currentAdDuration = p.scheduler.GetAdDuration(ad)
 					
 					p.mu.Lock()
-					p.stats.CurrentAdID = p.currentAd.ID
-					p.stats.CurrentAdType = p.currentAd.Type
+					p.stats.CurrentAdID = ad.ID
+					p.stats.CurrentAdType = ad.Type
 					p.stats.PlaybackStartTime = currentAdStartTime
 					p.mu.Unlock()
 					
 					log.Printf("[%s] [PLAYER] Playing ad: %s (type: %s, duration: %v)", 
-						time.Now().Format("15:04:05.000"), p.currentAd.ID, p.currentAd.Type, currentAdDuration)
+						time.Now().Format("15:04:05.000"), ad.ID, ad.Type, currentAdDuration)
 					
 					// Wait for transition delay before starting next ad
 					time.Sleep(p.scheduler.GetTransitionDelay())
@@ -259,14 +421,29 @@ func (p *Player) playbackLoop() {
 	}
 }
 
-// loadNextAd loads the next ad from the playlist
-func (p *Player) loadNextAd() {
+// loadNextAd loads the next ad from the playlist and returns it, or nil if none could be played
+func (p *Player) loadNextAd() *models.Ad {
 	p.mu.Lock()
+	// The previous ad ran for its scheduled duration
+	record := p.finishCurrentAdLocked(models.PlayOutcomeCompleted)
 	p.state = PlayerStateLoading
 	p.stats.State = PlayerStateLoading
 	p.mu.Unlock()
-	
-	ad := p.playlist.GetNextAd()
+	p.enqueueRecord(record)
+	
+	// An ad whose media isn't ready is skipped for the next one; only the first waits for its download
+	var ad *models.Ad
+	var localPath string
+	for attempt, attempts := 0, p.playlist.GetActiveCount(); attempt < attempts; attempt++ {
+		candidate := p.playlist.GetNextAd()
+		if candidate == nil {
+			break
+		}
+		if path, ok := p.mediaFor(candidate, attempt == 0); ok {
+			ad, localPath = candidate, path
+			break
+		}
+	}
 	if ad == nil {
 		p.mu.Lock()
 		p.currentAd = nil
@@ -274,23 +451,7 @@ func (p *Player) loadNextAd() {
 		p.stats.State = PlayerStatePlaying
 		p.mu.Unlock()
 		log.Printf("[%s] [PLAYER] No active ads available", time.Now().Format("15:04:05.000"))
-		return
-	}
-	
-	// Download media if needed
-	localPath, err := p.downloader.DownloadAdMedia(ad)
-	if err != nil {
-		log.Printf("[%s] [PLAYER] Failed to download media for ad %s: %v", 
-			time.Now().Format("15:04:05.000"), ad.ID, err)
-		// Try to use cached version if available
-		if cachedPath, exists := p.downloader.GetLocalPath(ad); exists {
-			localPath = cachedPath
-			log.Printf("[%s] [PLAYER] Using cached media: %s", time.Now().Format("15:04:05.000"), localPath)
-		} else {
-			log.Printf("[%s] [PLAYER] Skipping ad %s: no media available", 
-				time.Now().Format("15:04:05.000"), ad.ID)
-			return
-		}
+		return nil
 	}
 	
 	// Render the ad
@@ -307,19 +468,110 @@ func (p *Player) loadNextAd() {
 		p.stats.State = PlayerStateError
 		p.stats.LastError = err
 		p.mu.Unlock()
+		p.recordPlay(ad, time.Now(), time.Now(), p.renderer.CurrentName(), models.PlayOutcomeRenderError)
 		// Continue to next ad instead of returning
-		return
+		return nil
 	}
 	
 	log.Printf("[%s] [PLAYER] [OK] Successfully rendered ad %s", 
 		time.Now().Format("15:04:05.000"), ad.ID)
+	p.cache.Touch(ad)
 	
 	p.mu.Lock()
 	p.currentAd = ad
+	p.playStart = time.Now()
+	p.playRenderer = p.renderer.CurrentName()
+	p.playlistID = p.playlist.GetPlaylistID()
 	p.state = PlayerStatePlaying
 	p.stats.State = PlayerStatePlaying
 	p.stats.TotalAdsPlayed++
 	p.mu.Unlock()
+	return ad
+}
+
+// mediaFor returns the local media for an ad, queueing the download if it isn't cached
+// With wait set it gives the download mediaWaitTimeout; a slow download carries on in the
+// background while other ads play. Skipped ads are recorded as having no media
+func (p *Player) mediaFor(ad *models.Ad, wait bool) (string, bool) {
+	if localPath, cached := p.downloader.GetLocalPath(ad); cached {
+		return localPath, true
+	}
+	
+	p.downloads.Enqueue(*ad, time.Now())
+	if wait {
+		waitCtx, cancel := context.WithTimeout(p.ctx, mediaWaitTimeout)
+		localPath, err := p.downloads.Fetch(waitCtx, *ad, time.Now())
+		cancel()
+		if err == nil {
+			return localPath, true
+		}
+		log.Printf("[%s] [PLAYER] Failed to download media for ad %s: %v", 
+			time.Now().Format("15:04:05.000"), ad.ID, err)
+		// Try to use cached version if available
+		if cachedPath, exists := p.downloader.GetLocalPath(ad); exists {
+			log.Printf("[%s] [PLAYER] Using cached media: %s", time.Now().Format("15:04:05.000"), cachedPath)
+			return cachedPath, true
+		}
+	}
+	
+	log.Printf("[%s] [PLAYER] Skipping ad %s: no media available", 
+		time.Now().Format("15:04:05.000"), ad.ID)
+	p.recordPlay(ad, time.Now(), time.Now(), "", models.PlayOutcomeNoMedia)
+	return "", false
+}
+
+// finishCurrentAdLocked clears the ad on screen and returns its proof-of-play record, or nil
+// Callers must hold p.mu and pass the record to enqueueRecord after unlocking
+func (p *Player) finishCurrentAdLocked(outcome models.PlayOutcome) *models.PlayRecord {
+	if p.currentAd == nil {
+		return nil
+	}
+	ad := p.currentAd
+	start := p.playStart
+	p.currentAd = nil
+	p.playStart = time.Time{}
+
+	if p.eventQueue == nil || start.IsZero() {
+		return nil
+	}
+	return newPlayRecord(ad, p.playlistID, start, time.Now(), p.playRenderer, outcome)
+}
+
+// recordPlay records proof of play for an ad that never became the current ad
+func (p *Player) recordPlay(ad *models.Ad, start, end time.Time, renderer string, outcome models.PlayOutcome) {
+	p.enqueueRecord(newPlayRecord(ad, p.playlist.GetPlaylistID(), start, end, renderer, outcome))
+}
+
+// newPlayRecord builds a proof-of-play record
+func newPlayRecord(ad *models.Ad, playlistID string, start, end time.Time, renderer string, outcome models.PlayOutcome) *models.PlayRecord {
+	return &models.PlayRecord{
+		AdID:       ad.ID,
+		PlaylistID: playlistID,
+		Source:     ad.Source,
+		StartedAt:  start,
+		EndedAt:    end,
+		DurationMs: end.Sub(start).Milliseconds(),
+		Renderer:   renderer,
+		Outcome:    outcome,
+	}
+}
+
+// enqueueRecord writes a proof-of-play record to the event queue; a nil record is ignored
+// The queue writes to disk, so callers must not hold p.mu
+func (p *Player) enqueueRecord(record *models.PlayRecord) {
+	if record == nil {
+		return
+	}
+	p.mu.RLock()
+	queue := p.eventQueue
+	p.mu.RUnlock()
+	if queue == nil {
+		return
+	}
+	if err := queue.Enqueue(*record); err != nil {
+		log.Printf("[%s] [PLAYER] [WARN] Failed to queue proof of play for ad %s: %v",
+			time.Now().Format("15:04:05.000"), record.AdID, err)
+	}
 }
 
 // GetCurrentAd returns the currently playing ad
@@ -329,6 +581,16 @@ func (p *Player) GetCurrentAd() *models.Ad {
 	return p.currentAd
 }
 
+// GetDownloadStatus returns the queued, running and failed media downloads
+func (p *Player) GetDownloadStatus() DownloadStatus {
+	return p.downloads.Status()
+}
+
+// GetCacheStats returns media cache statistics
+func (p *Player) GetCacheStats() CacheStats {
+	return p.cache.GetStats()
+}
+
 // GetPlaylist returns the playlist instance
 func (p *Player) GetPlaylist() *Playlist {
 	return p.playlist
@@ -1,6 +1,7 @@
 package player
 
 import (
+	"mnemoCast-client/internal/ads"
 	"mnemoCast-client/internal/models"
 	"sort"
 	"sync"
@@ -10,37 +11,57 @@ import (
 // Playlist manages the list of ads and provides filtering/sorting
 type Playlist struct {
 	ads        []models.Ad
-	currentIdx int
+	playlistID string
 	lastUpdate time.Time
 	mu         sync.RWMutex
+	
+	// Rotation position of each source's ads, keyed by ad source
+	rotations map[string]*rotation
+	
+	// Ad sources sharing airtime (see sources.go)
+	sources []models.SourceWeight
+	credits map[string]int // Smooth weighted round-robin state per source
+	
+	// Offline policy (see offline.go)
+	maxOfflineAge time.Duration
+	lastFetch     func() time.Time // Last time the server confirmed the playlist; nil disables the policy
+	fallback      []models.Ad
+	offline       OfflineStatus
 }
 
 // NewPlaylist creates a new playlist
 func NewPlaylist() *Playlist {
 	return &Playlist{
 		ads:        []models.Ad{},
-		currentIdx: 0,
 		lastUpdate: time.Time{},
+		rotations:  make(map[string]*rotation),
+		credits:    make(map[string]int),
 	}
 }
 
-// UpdateAds updates the playlist with new ads from the server
-func (p *Playlist) UpdateAds(adResponse *models.AdDeliveryResponse) {
+// UpdateAds updates the playlist with new ads from the server and returns what changed
+// The rotation position is kept by ad ID, so an update doesn't restart the loop
+func (p *Playlist) UpdateAds(adResponse *models.AdDeliveryResponse) *ads.PlaylistDiff {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	previous := &models.AdDeliveryResponse{PlaylistID: p.playlistID, Ads: p.ads}
+	diff := ads.DiffPlaylists(previous, adResponse)
+	
 	p.ads = adResponse.Ads
+	p.playlistID = adResponse.PlaylistID
+	p.sources = adResponse.Sources
 	p.lastUpdate = time.Now()
 	
-	// Reset current index if it's out of bounds
-	if p.currentIdx >= len(p.ads) {
-		p.currentIdx = 0
-	}
+	return diff
 }
 
-// GetActiveAds returns ads that are currently active (within their time window)
+// GetActiveAds returns the ads that would play now: those within their time window and
+// not expired by the offline policy, or the fallback ads if the policy dropped everything
 func (p *Playlist) GetActiveAds() []models.Ad {
-	return p.FilterByTime(time.Now())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playableLocked(time.Now())
 }
 
 // FilterByTime filters ads based on current time and their startTime/endTime
@@ -48,10 +69,19 @@ func (p *Playlist) GetActiveAds() []models.Ad {
 func (p *Playlist) FilterByTime(now time.Time) []models.Ad {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	
+	return p.filterByTimeLocked(now)
+}
+
+// filterByTimeLocked is FilterByTime for callers already holding p.mu
+func (p *Playlist) filterByTimeLocked(now time.Time) []models.Ad {
+	return filterByTime(p.ads, now)
+}
+
+// filterByTime returns the ads whose time window includes now
+func filterByTime(ads []models.Ad, now time.Time) []models.Ad {
 	var activeAds []models.Ad
 	
-	for _, ad := range p.ads {
+	for _, ad := range ads {
 		// If ad has no time constraints, it's always active
 		if ad.StartTime.IsZero() && ad.EndTime.IsZero() {
 			activeAds = append(activeAds, ad)
@@ -94,26 +124,45 @@ func (p *Playlist) GetNextAd() *models.Ad {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
-	// Get active ads filtered by time
+	// Get active ads filtered by time and the offline policy
 	now := time.Now()
-	activeAds := p.FilterByTime(now)
+	activeAds := p.playableLocked(now)
 	
 	if len(activeAds) == 0 {
 		return nil
 	}
 	
+	// With several sources, pick whose turn it is and rotate through its ads only
+	source := p.nextSourceLocked(activeAds)
+	var sourceAds []models.Ad
+	for _, ad := range activeAds {
+		if ad.Source == source {
+			sourceAds = append(sourceAds, ad)
+		}
+	}
+	
 	// Sort by priority
-	sortedAds := p.SortByPriority(activeAds)
-	
-	// If we have ads, cycle through them
-	if len(sortedAds) > 0 {
-		// Find current ad in sorted list, or start from beginning
-		ad := sortedAds[p.currentIdx%len(sortedAds)]
-		p.currentIdx++
-		return &ad
+	sortedAds := p.SortByPriority(sourceAds)
+	
+	// Continue after the last ad played; if it's gone, the ad that took its place is next
+	position, ok := p.rotations[source]
+	if !ok {
+		position = &rotation{}
+		p.rotations[source] = position
 	}
+	next := position.lastPos
+	for i, ad := range sortedAds {
+		if ad.ID == position.lastAdID {
+			next = i + 1
+			break
+		}
+	}
+	next %= len(sortedAds)
 	
-	return nil
+	ad := sortedAds[next]
+	position.lastAdID = ad.ID
+	position.lastPos = next
+	return &ad
 }
 
 // GetCount returns the total number of ads in the playlist
@@ -128,6 +177,22 @@ func (p *Playlist) GetActiveCount() int {
 	return len(p.GetActiveAds())
 }
 
+// GetAds returns a copy of all ads in the playlist
+func (p *Playlist) GetAds() []models.Ad {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	all := make([]models.Ad, len(p.ads))
+	copy(all, p.ads)
+	return all
+}
+
+// GetPlaylistID returns the server playlist ID of the current ads
+func (p *Playlist) GetPlaylistID() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.playlistID
+}
+
 // GetLastUpdate returns the time of the last playlist update
 func (p *Playlist) GetLastUpdate() time.Time {
 	p.mu.RLock()
@@ -139,6 +204,7 @@ func (p *Playlist) GetLastUpdate() time.Time {
 func (p *Playlist) Reset() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.currentIdx = 0
+	p.rotations = make(map[string]*rotation)
+	p.credits = make(map[string]int)
 }
 
@@ -0,0 +1,192 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mnemoCast-client/internal/ads"
+	"mnemoCast-client/internal/models"
+	"sync"
+	"time"
+)
+
+// Prefetch settings used when the config leaves them unset
+const (
+	DefaultPrefetchReadyFraction = 1.0
+	DefaultPrefetchTimeout       = 10 * time.Minute
+)
+
+// prefetchRetryDelay is the wait before retrying downloads that failed during a prefetch
+const prefetchRetryDelay = 30 * time.Second
+
+// stagePlaylist prefetches a new playlist's media and activates it once enough of it is cached
+// A playlist still being prefetched is abandoned when a newer one arrives
+func (p *Player) stagePlaylist(adResponse *models.AdDeliveryResponse) {
+	p.mu.Lock()
+	
+	p.prefetchSeq++
+	seq := p.prefetchSeq
+	if p.prefetchCancel != nil {
+		p.prefetchCancel()
+		p.prefetchCancel = nil
+	}
+	
+	current := &models.AdDeliveryResponse{Ads: p.playlist.GetAds()}
+	if len(current.Ads) == 0 || p.ctx.Err() != nil || ads.DiffPlaylists(current, adResponse).IsEmpty() {
+		// Nothing is playing that's worth keeping, the player is stopped, or no ad changed: swap right away
+		p.clearPrefetchStatsLocked()
+		diff := p.activatePlaylistLocked(adResponse)
+		p.mu.Unlock()
+		p.announcePlaylist(adResponse, diff)
+		return
+	}
+	
+	ctx, cancel := context.WithTimeout(p.ctx, p.prefetchTimeout())
+	p.prefetchCancel = cancel
+	p.pendingAds = adResponse.Ads
+	p.stats.PendingPlaylistID = adResponse.PlaylistID
+	p.stats.PrefetchReady = 0
+	p.stats.PrefetchTotal = len(adResponse.Ads)
+	p.stats.PrefetchStarted = time.Now()
+	// Added under the lock, where Stop can't be between cancelling p.ctx and waiting
+	p.wg.Add(1)
+	p.mu.Unlock()
+	
+	log.Printf("[%s] [PREFETCH] Staging playlist with %d ads; current playlist keeps playing", 
+		time.Now().Format("15:04:05.000"), len(adResponse.Ads))
+	
+	go p.prefetchPlaylist(ctx, cancel, seq, adResponse)
+}
+
+// prefetchPlaylist downloads media until the ready fraction is reached or the prefetch times out
+func (p *Player) prefetchPlaylist(ctx context.Context, cancel context.CancelFunc, seq uint64, adResponse *models.AdDeliveryResponse) {
+	defer p.wg.Done()
+	defer cancel()
+	
+	required := p.prefetchReadyFraction()
+	total := len(adResponse.Ads)
+	
+	// Make room before downloading; the pending ads are protected as in use
+	p.enforceCache()
+	
+	for {
+		ready := p.prefetchMedia(ctx, seq, adResponse.Ads)
+		if total == 0 || float64(ready)/float64(total) >= required {
+			log.Printf("[%s] [PREFETCH] Media ready for %d/%d ads", 
+				time.Now().Format("15:04:05.000"), ready, total)
+			break
+		}
+		
+		if err := ctx.Err(); err != nil {
+			if errors.Is(err, context.Canceled) {
+				// Superseded by a newer playlist or the player stopped
+				return
+			}
+			log.Printf("[%s] [PREFETCH] [WARN] Timed out with media for %d/%d ads; activating playlist anyway", 
+				time.Now().Format("15:04:05.000"), ready, total)
+			break
+		}
+		
+		log.Printf("[%s] [PREFETCH] Media ready for %d/%d ads (need %.0f%%), retrying in %v", 
+			time.Now().Format("15:04:05.000"), ready, total, required*100, prefetchRetryDelay)
+		select {
+		case <-ctx.Done():
+		case <-time.After(prefetchRetryDelay):
+		}
+	}
+	
+	p.mu.Lock()
+	if seq != p.prefetchSeq {
+		p.mu.Unlock()
+		return
+	}
+	p.prefetchCancel = nil
+	p.clearPrefetchStatsLocked()
+	diff := p.activatePlaylistLocked(adResponse)
+	p.mu.Unlock()
+	
+	p.announcePlaylist(adResponse, diff)
+}
+
+// prefetchMedia downloads every ad's media on the download pool and returns how many are ready
+func (p *Player) prefetchMedia(ctx context.Context, seq uint64, adList []models.Ad) int {
+	due := p.estimateDue(adList)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ready := 0
+	
+	for i := range adList {
+		ad := adList[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			
+			if _, err := p.downloads.Fetch(ctx, ad, due[ad.ID]); err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[%s] [PREFETCH] [WARN] Failed to fetch media for ad %s: %v", 
+						time.Now().Format("15:04:05.000"), ad.ID, err)
+				}
+				return
+			}
+			
+			mu.Lock()
+			ready++
+			count := ready
+			mu.Unlock()
+			p.setPrefetchProgress(seq, count)
+		}()
+	}
+	
+	wg.Wait()
+	return ready
+}
+
+// estimateDue estimates when each ad will first play if the ads started rotating now
+// Ads scheduled to start later are due at their start time
+func (p *Player) estimateDue(adList []models.Ad) map[string]time.Time {
+	due := make(map[string]time.Time, len(adList))
+	at := time.Now()
+	for _, ad := range p.playlist.SortByPriority(adList) {
+		if ad.StartTime.After(at) {
+			due[ad.ID] = ad.StartTime
+		} else {
+			due[ad.ID] = at
+		}
+		at = at.Add(p.scheduler.GetAdDuration(&ad))
+	}
+	return due
+}
+
+// setPrefetchProgress updates the ready count shown in stats, ignoring superseded prefetches
+func (p *Player) setPrefetchProgress(seq uint64, ready int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if seq == p.prefetchSeq && ready > p.stats.PrefetchReady {
+		p.stats.PrefetchReady = ready
+	}
+}
+
+// clearPrefetchStatsLocked forgets the pending playlist; callers must hold p.mu
+func (p *Player) clearPrefetchStatsLocked() {
+	p.pendingAds = nil
+	p.stats.PendingPlaylistID = ""
+	p.stats.PrefetchReady = 0
+	p.stats.PrefetchTotal = 0
+	p.stats.PrefetchStarted = time.Time{}
+}
+
+// prefetchReadyFraction returns the configured fraction of media needed before a swap
+func (p *Player) prefetchReadyFraction() float64 {
+	if p.config != nil && p.config.PrefetchReadyFraction > 0 && p.config.PrefetchReadyFraction <= 1 {
+		return p.config.PrefetchReadyFraction
+	}
+	return DefaultPrefetchReadyFraction
+}
+
+// prefetchTimeout returns how long to wait for media before activating a playlist anyway
+func (p *Player) prefetchTimeout() time.Duration {
+	if p.config != nil && p.config.PrefetchTimeout > 0 {
+		return time.Duration(p.config.PrefetchTimeout) * time.Second
+	}
+	return DefaultPrefetchTimeout
+}
@@ -2,6 +2,7 @@ package player
 
 import (
 	"fmt"
+	"strings"
 	"mnemoCast-client/internal/models"
 	"mnemoCast-client/internal/player/renderers"
 )
@@ -73,6 +74,16 @@ func (rm *RendererManager) Render(ad *models.Ad, localPath string) error {
 	return renderer.Render(ad, localPath)
 }
 
+// CurrentName returns a short name for the active renderer (e.g. "image"), or "" if none
+func (rm *RendererManager) CurrentName() string {
+	if rm.current == nil {
+		return ""
+	}
+	name := fmt.Sprintf("%T", rm.current)
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.ToLower(strings.TrimSuffix(name, "Renderer"))
+}
+
 // Stop stops the current renderer
 func (rm *RendererManager) Stop() error {
 	if rm.current != nil {
@@ -0,0 +1,83 @@
+package player
+
+import (
+	"mnemoCast-client/internal/models"
+	"sort"
+)
+
+// rotation is a position in the loop over one source's ads
+type rotation struct {
+	lastAdID string // ID of the ad last returned by GetNextAd
+	lastPos  int    // Its position in the rotation, used if that ad is removed
+}
+
+// GetSources returns the ad sources of the current playlist
+func (p *Playlist) GetSources() []models.SourceWeight {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	sources := make([]models.SourceWeight, len(p.sources))
+	copy(sources, p.sources)
+	return sources
+}
+
+// nextSourceLocked returns the source the next ad should come from
+// Only sources in the highest priority with playable ads take part; they alternate by smooth
+// weighted round-robin, so weights 3 and 1 play A A B A rather than A A A B.
+// Callers must hold p.mu for writing
+func (p *Playlist) nextSourceLocked(playable []models.Ad) string {
+	present := make(map[string]bool)
+	for _, ad := range playable {
+		present[ad.Source] = true
+	}
+	if len(present) == 1 {
+		for source := range present {
+			return source
+		}
+	}
+
+	// Sources in the order the playlist lists them, then any unlisted ones by name
+	var tier []models.SourceWeight
+	listed := make(map[string]bool)
+	for _, source := range p.sources {
+		listed[source.Name] = true
+		if present[source.Name] {
+			tier = append(tier, source)
+		}
+	}
+	var unlisted []string
+	for name := range present {
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	sort.Strings(unlisted)
+	for _, name := range unlisted {
+		tier = append(tier, models.SourceWeight{Name: name, Weight: 1})
+	}
+
+	top := tier[0].Priority
+	for _, source := range tier {
+		if source.Priority > top {
+			top = source.Priority
+		}
+	}
+
+	total := 0
+	best := ""
+	for _, source := range tier {
+		if source.Priority != top {
+			continue
+		}
+		weight := source.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		p.credits[source.Name] += weight
+		total += weight
+		if best == "" || p.credits[source.Name] > p.credits[best] {
+			best = source.Name
+		}
+	}
+	p.credits[best] -= total
+	return best
+}
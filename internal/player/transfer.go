@@ -0,0 +1,375 @@
+package player
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"mnemoCast-client/internal/retry"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStallTimeout is how long a download may go without receiving data before it is aborted
+const DefaultStallTimeout = 30 * time.Second
+
+// partialMaxAge is how long an interrupted download is kept for resuming
+const partialMaxAge = 24 * time.Hour
+
+// progressLogInterval limits how often a running download is logged
+const progressLogInterval = 10 * time.Second
+
+// transferBufferSize is the read size, and so the granularity of progress and rate limiting
+const transferBufferSize = 32 * 1024
+
+// errStalled is returned when a download receives no data for the stall timeout
+var errStalled = errors.New("download stalled")
+
+// DownloadProgress reports the state of a media download
+type DownloadProgress struct {
+	URL         string
+	Downloaded  int64   // Bytes in the partial file, including any resumed part
+	Total       int64   // Expected size, 0 if the server didn't say
+	Resumed     int64   // Bytes already present when this attempt started
+	BytesPerSec float64 // Average rate of this attempt
+	Done        bool
+}
+
+// partialMeta is stored next to a partial file so a resume only continues the same content
+type partialMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// SetStallTimeout sets how long a download may receive no data before it is aborted and retried
+func (d *Downloader) SetStallTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultStallTimeout
+	}
+	d.stallTimeout = timeout
+}
+
+// SetBandwidthLimit caps the combined rate of all media downloads in bytes per second (0 = unlimited)
+func (d *Downloader) SetBandwidthLimit(bytesPerSec int64) {
+	d.limiter.setRate(bytesPerSec)
+}
+
+// SetOnProgress sets a callback for download progress, called after every chunk
+func (d *Downloader) SetOnProgress(callback func(DownloadProgress)) {
+	d.progressMu.Lock()
+	defer d.progressMu.Unlock()
+	d.onProgress = callback
+}
+
+// downloadPartial downloads url into a .partial file in the temp directory,
+// returning the file's path, SHA-256 and size once the transfer is complete
+// A partial file left by an earlier attempt is continued with a Range request
+// when the server confirms (via If-Range) that the content hasn't changed.
+func (d *Downloader) downloadPartial(ctx context.Context, url string) (string, string, int64, error) {
+	if err := os.MkdirAll(d.storage.GetTempDir(), 0755); err != nil {
+		return "", "", 0, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	partialPath := d.partialPath(url)
+	offset, meta := d.resumeOffset(partialPath, url)
+
+	// Abort the request if no data arrives for the stall timeout
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var stalled atomic.Bool
+	watchdog := time.AfterFunc(d.stallTimeout, func() {
+		stalled.Store(true)
+		cancel()
+	})
+	defer watchdog.Stop()
+	wrap := func(err error) error {
+		if stalled.Load() {
+			return fmt.Errorf("%w: no data for %v", errStalled, d.stallTimeout)
+		}
+		return err
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set user agent
+	req.Header.Set("User-Agent", "MnemoCast-Client/1.0")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if meta.ETag != "" {
+			req.Header.Set("If-Range", meta.ETag)
+		} else {
+			req.Header.Set("If-Range", meta.LastModified)
+		}
+	}
+
+	// Execute request
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", "", 0, wrap(fmt.Errorf("failed to execute request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	// Check status code
+	var total int64
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			d.discardPartial(partialPath)
+			return "", "", 0, fmt.Errorf("unexpected Content-Range %q, restarting download", resp.Header.Get("Content-Range"))
+		}
+		total = size
+		log.Printf("[%s] [DOWNLOAD] Resuming %s at %d bytes", time.Now().Format("15:04:05.000"), url, offset)
+	case http.StatusOK:
+		// Full content: the server ignored the range or the content changed
+		offset = 0
+		total = resp.ContentLength
+		meta = partialMeta{
+			URL:          url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		d.discardPartial(partialPath)
+		return "", "", 0, fmt.Errorf("partial download no longer matches, restarting")
+	default:
+		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if !retry.RetryableStatus(resp.StatusCode) {
+			return "", "", 0, retry.Permanent(err)
+		}
+		return "", "", 0, err
+	}
+	if total < 0 {
+		total = 0
+	}
+
+	file, hash, err := d.openPartial(partialPath, offset, meta)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	progress := DownloadProgress{URL: url, Downloaded: offset, Total: total, Resumed: offset}
+	started := time.Now()
+	lastLog := started
+	buf := make([]byte, transferBufferSize)
+	for {
+		watchdog.Reset(d.stallTimeout)
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			// Time spent waiting for bandwidth isn't a stall
+			watchdog.Stop()
+			if err := d.limiter.wait(ctx, n); err != nil {
+				file.Close()
+				return "", "", 0, wrap(err)
+			}
+			if _, err := file.Write(buf[:n]); err != nil {
+				file.Close()
+				return "", "", 0, fmt.Errorf("failed to write file: %w", err)
+			}
+			hash.Write(buf[:n])
+
+			progress.Downloaded += int64(n)
+			if elapsed := time.Since(started).Seconds(); elapsed > 0 {
+				progress.BytesPerSec = float64(progress.Downloaded-offset) / elapsed
+			}
+			d.reportProgress(progress)
+			if time.Since(lastLog) >= progressLogInterval {
+				lastLog = time.Now()
+				logProgress(progress)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			file.Close()
+			return "", "", 0, wrap(fmt.Errorf("failed to read response: %w (%d bytes kept for resume)", readErr, progress.Downloaded))
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return "", "", 0, fmt.Errorf("failed to write file: %w", err)
+	}
+	if total > 0 && progress.Downloaded != total {
+		return "", "", 0, fmt.Errorf("download incomplete: got %d of %d bytes", progress.Downloaded, total)
+	}
+
+	progress.Done = true
+	d.reportProgress(progress)
+	os.Remove(partialPath + ".json")
+
+	return partialPath, hex.EncodeToString(hash.Sum(nil)), progress.Downloaded, nil
+}
+
+// resumeOffset returns how much of url is already in the partial file, or 0 if it can't be resumed
+func (d *Downloader) resumeOffset(partialPath, url string) (int64, partialMeta) {
+	var meta partialMeta
+	data, err := os.ReadFile(partialPath + ".json")
+	if err != nil || json.Unmarshal(data, &meta) != nil || meta.URL != url ||
+		(meta.ETag == "" && meta.LastModified == "") {
+		// Without a validator there's no way to know the content is unchanged
+		d.discardPartial(partialPath)
+		return 0, partialMeta{}
+	}
+
+	info, err := os.Stat(partialPath)
+	if err != nil {
+		return 0, partialMeta{}
+	}
+	return info.Size(), meta
+}
+
+// openPartial opens the partial file for appending at offset, hashing what is already there
+func (d *Downloader) openPartial(partialPath string, offset int64, meta partialMeta) (*os.File, hash.Hash, error) {
+	hash := sha256.New()
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		existing, err := os.Open(partialPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open partial download: %w", err)
+		}
+		_, err = io.CopyN(hash, existing, offset)
+		existing.Close()
+		if err != nil {
+			d.discardPartial(partialPath)
+			return nil, nil, fmt.Errorf("failed to read partial download: %w", err)
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+	} else {
+		data, err := json.Marshal(meta)
+		if err == nil {
+			err = os.WriteFile(partialPath+".json", data, 0644)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to write partial download metadata: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create destination file: %w", err)
+	}
+	return file, hash, nil
+}
+
+// partialPath returns the partial file used for url; it is stable so restarts can resume
+func (d *Downloader) partialPath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(d.storage.GetTempDir(), hex.EncodeToString(sum[:16])+".partial")
+}
+
+// discardPartial removes a partial file and its metadata
+func (d *Downloader) discardPartial(partialPath string) {
+	os.Remove(partialPath)
+	os.Remove(partialPath + ".json")
+}
+
+// reportProgress calls the progress callback if set
+func (d *Downloader) reportProgress(progress DownloadProgress) {
+	d.progressMu.RLock()
+	callback := d.onProgress
+	d.progressMu.RUnlock()
+	if callback != nil {
+		callback(progress)
+	}
+}
+
+// logProgress logs a running download
+func logProgress(progress DownloadProgress) {
+	if progress.Total > 0 {
+		log.Printf("[%s] [DOWNLOAD] %s: %d/%d bytes (%.0f%%, %.0f KB/s)",
+			time.Now().Format("15:04:05.000"), progress.URL, progress.Downloaded, progress.Total,
+			float64(progress.Downloaded)*100/float64(progress.Total), progress.BytesPerSec/1024)
+		return
+	}
+	log.Printf("[%s] [DOWNLOAD] %s: %d bytes (%.0f KB/s)",
+		time.Now().Format("15:04:05.000"), progress.URL, progress.Downloaded, progress.BytesPerSec/1024)
+}
+
+// parseContentRange parses "bytes start-end/size", returning start and size (0 if "*")
+func parseContentRange(header string) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rangePart, sizePart, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	startPart, _, ok := strings.Cut(rangePart, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if sizePart == "*" {
+		return start, 0, true
+	}
+	size, err := strconv.ParseInt(sizePart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// rateLimiter paces reads so all downloads together stay under a byte rate
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate int64     // Bytes per second, 0 = unlimited
+	next time.Time // When the next chunk may be read
+}
+
+// setRate changes the limit; 0 or less disables it
+func (l *rateLimiter) setRate(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bytesPerSec < 0 {
+		bytesPerSec = 0
+	}
+	l.rate = bytesPerSec
+}
+
+// wait blocks until n more bytes may be read
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,204 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"mnemoCast-client/internal/models"
+	"net/http"
+	"time"
+)
+
+// Policy describes how failed operations are retried
+// Delays grow exponentially from BaseDelay, are capped at MaxDelay and
+// randomized with full jitter so a fleet of screens doesn't retry in lockstep
+type Policy struct {
+	MaxRetries int           // Retries after the first attempt
+	BaseDelay  time.Duration // Delay ceiling for the first retry
+	MaxDelay   time.Duration // Upper bound for any single delay
+	Multiplier float64       // Growth factor between retries
+	Jitter     bool          // Randomize each delay in [0, ceiling)
+}
+
+// NewPolicy creates an exponential backoff policy with full jitter
+func NewPolicy(maxRetries int, baseDelay, maxDelay time.Duration) *Policy {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	if maxDelay < baseDelay {
+		maxDelay = baseDelay
+	}
+	return &Policy{
+		MaxRetries: maxRetries,
+		BaseDelay:  baseDelay,
+		MaxDelay:   maxDelay,
+		Multiplier: 2,
+		Jitter:     true,
+	}
+}
+
+// DefaultPolicy returns the policy used when no configuration is available
+func DefaultPolicy() *Policy {
+	return NewPolicy(3, 2*time.Second, 60*time.Second)
+}
+
+// FromConfig creates a policy from the screen's retry settings
+func FromConfig(config *models.ScreenConfig) *Policy {
+	if config == nil {
+		return DefaultPolicy()
+	}
+	return NewPolicy(
+		config.RetryAttempts,
+		time.Duration(config.RetryDelay)*time.Second,
+		time.Duration(config.RetryMaxDelay)*time.Second,
+	)
+}
+
+// Backoff returns the delay to wait before the given retry (1-based)
+func (p *Policy) Backoff(retry int) time.Duration {
+	if retry < 1 {
+		retry = 1
+	}
+
+	ceiling := float64(p.BaseDelay) * math.Pow(p.Multiplier, float64(retry-1))
+	if ceiling > float64(p.MaxDelay) || math.IsInf(ceiling, 0) {
+		ceiling = float64(p.MaxDelay)
+	}
+
+	if p.Jitter {
+		return time.Duration(rand.Float64() * ceiling)
+	}
+	return time.Duration(ceiling)
+}
+
+// Wait blocks for the backoff of the given retry or until ctx is cancelled
+func (p *Policy) Wait(ctx context.Context, retry int) error {
+	return Sleep(ctx, p.Backoff(retry))
+}
+
+// Do runs fn until it succeeds, fails permanently, retries are exhausted or ctx is cancelled
+// onRetry, if set, is called before each retry wait with the retry number, delay and last error
+func (p *Policy) Do(ctx context.Context, fn func(attempt int) error, onRetry func(retry int, delay time.Duration, err error)) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= p.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := p.Backoff(attempt)
+			// Honor a server-requested wait, but leave long waits to the caller's schedule
+			if wait := RetryAfter(lastErr); wait > 0 {
+				if wait > p.MaxDelay {
+					return &RetryAfterError{Wait: wait, MaxDelay: p.MaxDelay, Err: lastErr}
+				}
+				if wait > delay {
+					delay = wait
+				}
+			}
+			if onRetry != nil {
+				onRetry(attempt, delay, lastErr)
+			}
+			if err := Sleep(ctx, delay); err != nil {
+				return err
+			}
+		}
+
+		err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		// Stop early on cancellation or errors that won't go away by retrying
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if IsPermanent(err) {
+			return err
+		}
+	}
+
+	return lastErr
+}
+
+// Sleep waits for d or until ctx is cancelled, returning ctx.Err() in the latter case
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryableStatus reports whether an HTTP status is worth retrying
+// Server errors, timeouts and rate limiting are transient; auth and not-found failures are not
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return code >= 500 && code != http.StatusNotImplemented
+}
+
+// RetryAfter returns the wait requested by the server for err, or 0 if none
+// Errors opt in by implementing RetryAfter() time.Duration
+func RetryAfter(err error) time.Duration {
+	var hinted interface{ RetryAfter() time.Duration }
+	if errors.As(err, &hinted) {
+		return hinted.RetryAfter()
+	}
+	return 0
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err so Do returns it immediately instead of retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// RetryAfterError is returned by Do when the server asked for a longer wait than MaxDelay
+// Do gives up instead of blocking; RetryAfter still reports the requested wait so the
+// caller can schedule the next attempt itself
+type RetryAfterError struct {
+	Wait     time.Duration // Wait requested by the server
+	MaxDelay time.Duration // Longest wait the policy allows
+	Err      error         // Error of the last attempt
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("server asked to retry after %v, longer than the %v retry limit: %v", e.Wait, e.MaxDelay, e.Err)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the wait requested by the server
+func (e *RetryAfterError) RetryAfter() time.Duration {
+	return e.Wait
+}
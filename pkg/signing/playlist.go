@@ -0,0 +1,82 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Playlist signatures
+//
+// The ad server may wrap the ad delivery response in an envelope
+//
+//	{"payload": "<base64 playlist JSON>", "signature": "<base64 Ed25519 signature>", "keyId": "..."}
+//
+// The signature covers the exact payload bytes, so no canonical JSON form is needed.
+// Screens pin the server's public key and reject playlists that don't verify.
+
+var (
+	ErrUnsignedPlaylist     = errors.New("playlist is not signed")
+	ErrBadPlaylistSignature = errors.New("invalid playlist signature")
+)
+
+// Envelope is a signed playlist as delivered by the ad server
+type Envelope struct {
+	Payload   string `json:"payload"`         // Base64 playlist JSON
+	Signature string `json:"signature"`       // Base64 Ed25519 signature of the payload bytes
+	KeyID     string `json:"keyId,omitempty"` // Identifies the signing key, for rotation
+}
+
+// IsEnvelope reports whether the envelope fields are present
+func (e *Envelope) IsEnvelope() bool {
+	return e.Payload != "" || e.Signature != ""
+}
+
+// Decode returns the payload and signature bytes without verifying them
+func (e *Envelope) Decode() ([]byte, []byte, error) {
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid playlist payload encoding: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(e.Signature)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid playlist signature encoding: %w", err)
+	}
+	return payload, signature, nil
+}
+
+// SignPlaylist wraps playlist JSON in a signed envelope; it is the reference implementation for servers
+func SignPlaylist(privateKey ed25519.PrivateKey, keyID string, payload []byte) *Envelope {
+	return &Envelope{
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, payload)),
+		KeyID:     keyID,
+	}
+}
+
+// VerifyPlaylist checks an Ed25519 signature over playlist payload bytes
+func VerifyPlaylist(publicKey ed25519.PublicKey, payload, signature []byte) error {
+	if len(signature) == 0 {
+		return ErrUnsignedPlaylist
+	}
+	if len(signature) != ed25519.SignatureSize || !ed25519.Verify(publicKey, payload, signature) {
+		return ErrBadPlaylistSignature
+	}
+	return nil
+}
+
+// ParsePublicKey parses an Ed25519 public key given as base64 or hex
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		key, err = hex.DecodeString(s)
+	}
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("playlist public key must be %d bytes, base64 or hex encoded", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
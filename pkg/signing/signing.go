@@ -0,0 +1,121 @@
+// Package signing implements HMAC request signing for screen-to-server calls.
+//
+// Instead of sending the passkey, the client signs each request with
+// HMAC-SHA256(passkey, canonical string) where the canonical string is
+//
+//	v1\n<METHOD>\n<path?query>\n<unix timestamp>\n<nonce>\n<hex sha256 of body>
+//
+// The server recomputes the signature with its copy of the passkey. The
+// timestamp bounds how long a captured request stays valid and the nonce
+// prevents it from being replayed within that window.
+package signing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version identifies the signing scheme
+const Version = "v1"
+
+// Header names used by signed requests
+const (
+	HeaderScreenID      = "X-Screen-Id"
+	HeaderVersion       = "X-Signature-Version"
+	HeaderTimestamp     = "X-Signature-Timestamp"
+	HeaderNonce         = "X-Signature-Nonce"
+	HeaderContentSHA256 = "X-Content-Sha256"
+	HeaderSignature     = "X-Signature"
+)
+
+// Sign adds signature headers to req for the given body
+// A fresh nonce and timestamp are used on every call, so retries must be re-signed
+func Sign(req *http.Request, screenID, passkey string, body []byte, now time.Time) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	bodyHash := HashBody(body)
+	canonical := CanonicalString(req.Method, requestPath(req), timestamp, nonce, bodyHash)
+
+	req.Header.Set(HeaderScreenID, screenID)
+	req.Header.Set(HeaderVersion, Version)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderContentSHA256, bodyHash)
+	req.Header.Set(HeaderSignature, ComputeSignature(passkey, canonical))
+	return nil
+}
+
+// CanonicalString builds the string that is signed
+func CanonicalString(method, path, timestamp, nonce, bodyHash string) string {
+	return strings.Join([]string{Version, strings.ToUpper(method), path, timestamp, nonce, bodyHash}, "\n")
+}
+
+// ComputeSignature returns the hex HMAC-SHA256 of the canonical string keyed by the passkey
+func ComputeSignature(passkey, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(passkey))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HashBody returns the hex SHA-256 of a request body (empty bodies hash the empty string)
+func HashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadBody returns the body of req without consuming it
+func ReadBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// requestPath returns the escaped path plus raw query that is covered by the signature
+func requestPath(req *http.Request) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	return path
+}
+
+// newNonce generates a random 128-bit nonce
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,156 @@
+package signing
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMissingSignature  = errors.New("missing signature headers")
+	ErrUnsupportedScheme = errors.New("unsupported signature version")
+	ErrClockSkew         = errors.New("request timestamp outside allowed clock skew")
+	ErrReplayed          = errors.New("request nonce already used")
+	ErrBodyMismatch      = errors.New("body hash does not match body")
+	ErrBadSignature      = errors.New("invalid signature")
+)
+
+// DefaultMaxSkew is the default tolerated difference between client and server clocks
+const DefaultMaxSkew = 5 * time.Minute
+
+// PasskeyLookup returns the passkey registered for a screen
+type PasskeyLookup func(screenID string) (string, error)
+
+// Verifier validates signed requests; it is the reference implementation for servers
+// Nonces are remembered for twice the skew window so a request can't be replayed while its timestamp is valid
+type Verifier struct {
+	lookup  PasskeyLookup
+	maxSkew time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]time.Time // nonce -> expiry
+	order  []usedNonce          // Nonces in the order they were used, so the oldest expire first
+}
+
+// usedNonce is a remembered nonce and when it may be forgotten
+type usedNonce struct {
+	key    string
+	expiry time.Time
+}
+
+// NewVerifier creates a verifier; maxSkew <= 0 uses DefaultMaxSkew
+func NewVerifier(lookup PasskeyLookup, maxSkew time.Duration) *Verifier {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	return &Verifier{
+		lookup:  lookup,
+		maxSkew: maxSkew,
+		now:     time.Now,
+		nonces:  make(map[string]time.Time),
+	}
+}
+
+// Verify checks the signature of req and returns the authenticated screen ID
+// The request body is left readable for the next handler
+func (v *Verifier) Verify(req *http.Request) (string, error) {
+	screenID := req.Header.Get(HeaderScreenID)
+	version := req.Header.Get(HeaderVersion)
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	bodyHash := req.Header.Get(HeaderContentSHA256)
+	signature := req.Header.Get(HeaderSignature)
+
+	if screenID == "" || timestamp == "" || nonce == "" || bodyHash == "" || signature == "" {
+		return "", ErrMissingSignature
+	}
+	if version != Version {
+		return "", ErrUnsupportedScheme
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed timestamp", ErrClockSkew)
+	}
+	now := v.now()
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew > v.maxSkew || skew < -v.maxSkew {
+		return "", fmt.Errorf("%w: off by %v", ErrClockSkew, skew.Round(time.Second))
+	}
+
+	body, err := ReadBody(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read body: %w", err)
+	}
+	if !hmac.Equal([]byte(HashBody(body)), []byte(bodyHash)) {
+		return "", ErrBodyMismatch
+	}
+
+	passkey, err := v.lookup(screenID)
+	if err != nil {
+		return "", fmt.Errorf("%w: unknown screen", ErrBadSignature)
+	}
+
+	canonical := CanonicalString(req.Method, requestPath(req), timestamp, nonce, bodyHash)
+	expected := ComputeSignature(passkey, canonical)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", ErrBadSignature
+	}
+
+	// Only remember nonces of authentic requests so forged ones can't fill the cache
+	if !v.useNonce(screenID+":"+nonce, now) {
+		return "", ErrReplayed
+	}
+
+	return screenID, nil
+}
+
+// useNonce records a nonce, returning false if it was already seen
+// Expired nonces are dropped from the front of the list, so a call costs no more than what it expires
+func (v *Verifier) useNonce(key string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Every nonce lives for the same window, so expired ones are all at the front
+	expired := 0
+	for expired < len(v.order) && now.After(v.order[expired].expiry) {
+		delete(v.nonces, v.order[expired].key)
+		expired++
+	}
+	v.order = v.order[expired:]
+
+	if _, seen := v.nonces[key]; seen {
+		return false
+	}
+	expiry := now.Add(2 * v.maxSkew)
+	v.nonces[key] = expiry
+	v.order = append(v.order, usedNonce{key: key, expiry: expiry})
+	return true
+}
+
+type screenIDKey struct{}
+
+// Middleware rejects requests without a valid signature with 401
+// The authenticated screen ID is available to next via ScreenIDFromContext
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		screenID, err := v.Verify(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), screenIDKey{}, screenID)))
+	})
+}
+
+// ScreenIDFromContext returns the screen ID authenticated by Middleware
+func ScreenIDFromContext(ctx context.Context) (string, bool) {
+	screenID, ok := ctx.Value(screenIDKey{}).(string)
+	return screenID, ok
+}
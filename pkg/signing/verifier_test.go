@@ -0,0 +1,139 @@
+package signing
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, 12, 19, 1, 24, 10, 0, time.UTC)
+
+func newTestVerifier() *Verifier {
+	verifier := NewVerifier(func(screenID string) (string, error) {
+		if screenID != "screen-1" {
+			return "", fmt.Errorf("unknown screen %s", screenID)
+		}
+		return "passkey", nil
+	}, time.Minute)
+	verifier.now = func() time.Time { return testNow }
+	return verifier
+}
+
+// signedRequest builds a request signed with passkey at the given time
+func signedRequest(t *testing.T, target, body, passkey string, at time.Time) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if err := Sign(req, "screen-1", passkey, []byte(body), at); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return req
+}
+
+func TestVerifyAcceptsSignedRequestAndKeepsBody(t *testing.T) {
+	verifier := newTestVerifier()
+	req := signedRequest(t, "/api/v1/screens/screen-1/heartbeat?full=1", `{"status":"ok"}`, "passkey", testNow.Add(-30*time.Second))
+
+	screenID, err := verifier.Verify(req)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if screenID != "screen-1" {
+		t.Errorf("screenID = %q, want screen-1", screenID)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"status":"ok"}` {
+		t.Errorf("body = %q, want it left readable", body)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(t *testing.T) *http.Request
+		wantErr error
+	}{
+		{"wrong passkey", func(t *testing.T) *http.Request {
+			return signedRequest(t, "/events", "", "other-passkey", testNow)
+		}, ErrBadSignature},
+		{"timestamp too old", func(t *testing.T) *http.Request {
+			return signedRequest(t, "/events", "", "passkey", testNow.Add(-2*time.Minute))
+		}, ErrClockSkew},
+		{"timestamp in the future", func(t *testing.T) *http.Request {
+			return signedRequest(t, "/events", "", "passkey", testNow.Add(2*time.Minute))
+		}, ErrClockSkew},
+		{"tampered body", func(t *testing.T) *http.Request {
+			req := signedRequest(t, "/events", `{"n":1}`, "passkey", testNow)
+			req.Body = io.NopCloser(strings.NewReader(`{"n":2}`))
+			return req
+		}, ErrBodyMismatch},
+		{"tampered body and hash", func(t *testing.T) *http.Request {
+			req := signedRequest(t, "/events", `{"n":1}`, "passkey", testNow)
+			req.Body = io.NopCloser(strings.NewReader(`{"n":2}`))
+			req.Header.Set(HeaderContentSHA256, HashBody([]byte(`{"n":2}`)))
+			return req
+		}, ErrBadSignature},
+		{"tampered query", func(t *testing.T) *http.Request {
+			req := signedRequest(t, "/events?limit=1", "", "passkey", testNow)
+			req.URL.RawQuery = "limit=1000"
+			return req
+		}, ErrBadSignature},
+		{"unknown screen", func(t *testing.T) *http.Request {
+			req := signedRequest(t, "/events", "", "passkey", testNow)
+			req.Header.Set(HeaderScreenID, "screen-2")
+			return req
+		}, ErrBadSignature},
+		{"missing signature", func(t *testing.T) *http.Request {
+			req := signedRequest(t, "/events", "", "passkey", testNow)
+			req.Header.Del(HeaderSignature)
+			return req
+		}, ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestVerifier().Verify(tt.build(t))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsReplayedNonce(t *testing.T) {
+	verifier := newTestVerifier()
+	req := signedRequest(t, "/events", `{"n":1}`, "passkey", testNow)
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(strings.NewReader(`{"n":1}`))
+
+	if _, err := verifier.Verify(req); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if _, err := verifier.Verify(replay); !errors.Is(err, ErrReplayed) {
+		t.Errorf("replay err = %v, want %v", err, ErrReplayed)
+	}
+}
+
+func TestVerifierForgetsExpiredNonces(t *testing.T) {
+	verifier := newTestVerifier()
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(signedRequest(t, "/events", "", "passkey", testNow)); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+
+	// Nonces are kept for twice the skew window, after which their timestamps are rejected anyway
+	later := testNow.Add(2*time.Minute + time.Second)
+	verifier.now = func() time.Time { return later }
+	if _, err := verifier.Verify(signedRequest(t, "/events", "", "passkey", later)); err != nil {
+		t.Fatalf("request after the window: %v", err)
+	}
+
+	if len(verifier.nonces) != 1 || len(verifier.order) != 1 {
+		t.Errorf("remembered %d nonces (%d ordered), want only the latest", len(verifier.nonces), len(verifier.order))
+	}
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BackupSuffix is appended to a file's path to name its last-known-good copy
+const BackupSuffix = ".bak"
+
+// WriteFileAtomic replaces path with data so that a crash leaves either the old or the new contents
+// Data goes to a temp file in the same directory, which is synced and renamed over path;
+// the directory is then synced so the rename itself survives a power cut
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	// Remove the temp file on any failure before the rename
+	committed := false
+	defer func() {
+		if !committed {
+			os.Remove(tmpName)
+		}
+	}()
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set temp file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+	committed = true
+
+	syncDir(dir)
+	return nil
+}
+
+// WriteFileWithBackup atomically replaces path, first saving its current contents as path+BackupSuffix
+// The current contents only replace the backup if validate accepts them, so a damaged primary
+// never overwrites the last-known-good copy. A nil validate accepts any contents.
+func WriteFileWithBackup(path string, data []byte, perm os.FileMode, validate func([]byte) error) error {
+	current, err := os.ReadFile(path)
+	if err == nil {
+		if validate == nil || validate(current) == nil {
+			if err := WriteFileAtomic(path+BackupSuffix, current, perm); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", filepath.Base(path), err)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s for backup: %w", filepath.Base(path), err)
+	}
+
+	return WriteFileAtomic(path, data, perm)
+}
+
+// ReadFileWithBackup reads path and passes its contents to parse
+// If the primary file is missing, unreadable or rejected by parse, the backup copy is tried instead
+// and, when it parses, restored over the primary. restored reports whether the backup was used.
+// parse may run twice, so it should only publish its result on success.
+// When neither file can be used the primary's error is returned, so os.IsNotExist still works for callers.
+func ReadFileWithBackup(path string, parse func([]byte) error) (restored bool, err error) {
+	restored, err = readFileOrBackup(path, parse)
+	if restored {
+		// Put the last-known-good copy back so the next write backs up good data
+		// A failed restore isn't fatal; the backup is tried again on the next read
+		if backup, err := os.ReadFile(path + BackupSuffix); err == nil {
+			WriteFileAtomic(path, backup, 0600)
+		}
+	}
+	return restored, err
+}
+
+// readFileOrBackup passes the contents of path to parse, or of its backup copy if that fails
+// Nothing is written; usedBackup reports whether the backup was parsed
+func readFileOrBackup(path string, parse func([]byte) error) (usedBackup bool, err error) {
+	data, primaryErr := os.ReadFile(path)
+	if primaryErr == nil {
+		if primaryErr = parse(data); primaryErr == nil {
+			return false, nil
+		}
+	}
+
+	backup, err := os.ReadFile(path + BackupSuffix)
+	if err != nil {
+		return false, primaryErr
+	}
+	if err := parse(backup); err != nil {
+		return false, primaryErr
+	}
+	return true, nil
+}
+
+// RemoveWithBackup deletes path and its backup copy
+func RemoveWithBackup(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(path + BackupSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ExistsWithBackup reports whether path or its backup copy exists
+func ExistsWithBackup(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	_, err := os.Stat(path + BackupSuffix)
+	return err == nil
+}
+
+// syncDir flushes a directory entry so renames within it are durable
+// Best effort: some platforms (Windows) can't sync directories, and the rename has already happened
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	d.Sync()
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultLockTimeout is how long OpenBolt waits for another process to release the database
+const DefaultLockTimeout = 5 * time.Second
+
+// ErrInUse is returned by OpenBolt when another process holds the database
+var ErrInUse = errors.New("in use by another process")
+
+// BoltStore is a Store backed by a bbolt database file
+// The database stays open for the life of the store; bbolt locks the file, so another
+// process (such as the test-ads tool) can't open it until the store is closed.
+// Such a process can read a copy written with WriteSnapshot instead.
+type BoltStore struct {
+	path string
+	db   *bolt.DB
+}
+
+// OpenBolt opens the database at path, creating the file if needed
+func OpenBolt(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	return openBolt(path, &bolt.Options{Timeout: DefaultLockTimeout})
+}
+
+// OpenBoltReadOnly opens an existing database without write access
+// Updates fail; it is meant for copies written with WriteSnapshot, which no process locks
+func OpenBoltReadOnly(path string) (*BoltStore, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", filepath.Base(path), err)
+	}
+	return openBolt(path, &bolt.Options{Timeout: DefaultLockTimeout, ReadOnly: true})
+}
+
+// openBolt opens the database at path with options
+func openBolt(path string, options *bolt.Options) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, options)
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("database %s is %w", filepath.Base(path), ErrInUse)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", filepath.Base(path), err)
+	}
+
+	return &BoltStore{path: path, db: db}, nil
+}
+
+// WriteSnapshot writes a consistent copy of the database to path, replacing it atomically
+// It runs in a read transaction, so writers carry on while the copy is made
+func (s *BoltStore) WriteSnapshot(path string) error {
+	var buf bytes.Buffer
+	err := s.db.View(func(btx *bolt.Tx) error {
+		_, err := btx.WriteTo(&buf)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+	return WriteFileAtomic(path, buf.Bytes(), 0600)
+}
+
+// Path returns the database file path
+func (s *BoltStore) Path() string {
+	return s.path
+}
+
+// View runs fn in a read-only transaction
+func (s *BoltStore) View(fn func(tx Tx) error) error {
+	return s.db.View(func(btx *bolt.Tx) error {
+		return fn(&boltTx{tx: btx})
+	})
+}
+
+// Update runs fn in a read-write transaction
+func (s *BoltStore) Update(fn func(tx Tx) error) error {
+	return s.db.Update(func(btx *bolt.Tx) error {
+		return fn(&boltTx{tx: btx})
+	})
+}
+
+// Close closes the database, waiting for running transactions to finish
+func (s *BoltStore) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	return nil
+}
+
+// boltTx adapts a bbolt transaction to Tx
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (t *boltTx) Get(bucket, key string) ([]byte, error) {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil, ErrNotFound
+	}
+	value := b.Get([]byte(key))
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	// bbolt values are only valid for the life of the transaction
+	return append([]byte(nil), value...), nil
+}
+
+func (t *boltTx) Put(bucket, key string, value []byte) error {
+	b, err := t.tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+	}
+	return b.Put([]byte(key), value)
+}
+
+func (t *boltTx) Delete(bucket, key string) error {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+	return b.Delete([]byte(key))
+}
+
+func (t *boltTx) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(k, v []byte) error {
+		return fn(string(k), append([]byte(nil), v...))
+	})
+}
+
+func (t *boltTx) Count(bucket string) (int, error) {
+	b := t.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return 0, nil
+	}
+	return b.Stats().KeyN, nil
+}
+
+func (t *boltTx) CreateBucket(bucket string) error {
+	if _, err := t.tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+	}
+	return nil
+}
+
+func (t *boltTx) DeleteBucket(bucket string) error {
+	err := t.tx.DeleteBucket([]byte(bucket))
+	if errors.Is(err, bolt.ErrBucketNotFound) {
+		return nil
+	}
+	return err
+}
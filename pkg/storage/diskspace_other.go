@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package storage
+
+import "errors"
+
+// FreeSpace is not supported on this platform; the disk-space guard is skipped
+func FreeSpace(path string) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
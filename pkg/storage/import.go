@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// importsBucket records which files have been imported and their modification time
+const importsBucket = "_imports"
+
+// ImportFile loads a legacy or hand-placed file into the store
+// The file is imported when it is newer than the last import of the same path, so replacing
+// it (for example copying in a known-good playlist) imports it again on the next start.
+// Older versions wrote these files with WriteFileWithBackup, so when the primary is missing or
+// damaged its last-known-good backup is imported instead; the files themselves are never modified.
+// parse runs inside the same transaction that records the import and may run twice if the
+// primary file is damaged and its backup is tried. Returns false if there was nothing to import.
+func ImportFile(store Store, path string, parse func(tx Tx, data []byte) error) (bool, error) {
+	if !ExistsWithBackup(path) {
+		return false, nil
+	}
+
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		info, err = os.Stat(path + BackupSuffix)
+		if err != nil {
+			return false, nil
+		}
+	}
+	modTime := info.ModTime().UTC()
+
+	var lastImport time.Time
+	err = store.View(func(tx Tx) error {
+		data, err := tx.Get(importsBucket, key)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return lastImport.UnmarshalText(data)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to read import record: %w", err)
+	}
+	if !modTime.After(lastImport) {
+		return false, nil
+	}
+
+	err = store.Update(func(tx Tx) error {
+		if _, err := readFileOrBackup(path, func(data []byte) error {
+			return parse(tx, data)
+		}); err != nil {
+			return err
+		}
+
+		stamp, err := modTime.MarshalText()
+		if err != nil {
+			return err
+		}
+		return tx.Put(importsBucket, key, stamp)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to import %s: %w", filepath.Base(path), err)
+	}
+
+	return true, nil
+}
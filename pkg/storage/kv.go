@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by Tx.Get when a key does not exist
+var ErrNotFound = errors.New("key not found")
+
+// Store is an embedded key-value store organised into named buckets
+// All reads happen inside View and all writes inside Update; an Update either
+// commits completely or not at all
+type Store interface {
+	View(fn func(tx Tx) error) error
+	Update(fn func(tx Tx) error) error
+	Close() error
+}
+
+// Tx is a transaction on a Store
+// Buckets are created on first write; reading a missing bucket behaves like an empty one
+type Tx interface {
+	Get(bucket, key string) ([]byte, error)
+	Put(bucket, key string, value []byte) error
+	Delete(bucket, key string) error
+	// ForEach visits keys in ascending byte order; returning an error stops the walk
+	ForEach(bucket string, fn func(key string, value []byte) error) error
+	// Count returns the number of keys in bucket
+	Count(bucket string) (int, error)
+	// CreateBucket creates bucket if it doesn't exist yet
+	CreateBucket(bucket string) error
+	// DeleteBucket removes bucket and all of its keys
+	DeleteBucket(bucket string) error
+}
+
+// metaBucket holds store-level bookkeeping such as the schema version
+const metaBucket = "_meta"
+
+// schemaVersionKey is the key of the schema version in metaBucket
+const schemaVersionKey = "schema_version"
+
+// Migration upgrades the store schema to Version
+type Migration struct {
+	Version int
+	Name    string
+	Apply   func(tx Tx) error
+}
+
+// SchemaVersion returns the schema version recorded in the store (0 for a new store)
+func SchemaVersion(store Store) (int, error) {
+	version := 0
+	err := store.View(func(tx Tx) error {
+		var err error
+		version, err = readSchemaVersion(tx)
+		return err
+	})
+	return version, err
+}
+
+// Migrate applies, in order, every migration newer than the store's schema version
+// Each migration runs in its own transaction together with the version bump,
+// so an interrupted upgrade resumes from the last completed step
+func Migrate(store Store, migrations []Migration) error {
+	for i, migration := range migrations {
+		if i > 0 && migration.Version <= migrations[i-1].Version {
+			return fmt.Errorf("migrations out of order at version %d", migration.Version)
+		}
+
+		err := store.Update(func(tx Tx) error {
+			current, err := readSchemaVersion(tx)
+			if err != nil {
+				return err
+			}
+			if migration.Version <= current {
+				return nil
+			}
+
+			if err := migration.Apply(tx); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+			}
+
+			return tx.Put(metaBucket, schemaVersionKey, encodeUint(uint64(migration.Version)))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readSchemaVersion reads the schema version inside a transaction
+func readSchemaVersion(tx Tx) (int, error) {
+	data, err := tx.Get(metaBucket, schemaVersionKey)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 8 {
+		return 0, fmt.Errorf("corrupt schema version")
+	}
+	return int(binary.BigEndian.Uint64(data)), nil
+}
+
+// SequenceKey formats n as a fixed-width key so keys sort numerically
+func SequenceKey(n uint64) string {
+	return fmt.Sprintf("%020d", n)
+}
+
+// encodeUint encodes n as 8 big-endian bytes
+func encodeUint(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
@@ -3,18 +3,20 @@
 echo "=== Player Status Check ==="
 echo
 
-# Check if ads exist
-ADS_FILE="$HOME/.mnemocast/ads/current_ads.json"
-if [ -f "$ADS_FILE" ]; then
-    echo "✓ Ads file exists: $ADS_FILE"
-    AD_COUNT=$(jq '.ads | length' "$ADS_FILE" 2>/dev/null || echo "0")
-    echo "  Ads count: $AD_COUNT"
-    if [ "$AD_COUNT" -gt 0 ]; then
-        echo "  First ad:"
-        jq '.ads[0] | {id, type, contentUrl, duration}' "$ADS_FILE" 2>/dev/null || echo "    (Could not parse)"
+# Check if ads exist (playlists are stored in the screen database)
+DB_FILE="$HOME/.mnemocast/mnemocast.db"
+if [ -f "$DB_FILE" ]; then
+    echo "✓ Screen database exists: $DB_FILE"
+    # While the screen runs, test-ads reads the snapshot it exports every 30 seconds
+    if [ -x ./bin/test-ads ]; then
+        ./bin/test-ads list 2>/dev/null | head -14 | sed 's/^/  /'
+        echo "  Recent heartbeats:"
+        ./bin/test-ads heartbeats 5 2>/dev/null | sed 's/^/    /'
+    else
+        echo "  Build ./bin/test-ads and run './bin/test-ads list' to see the playlist"
     fi
 else
-    echo "✗ Ads file not found: $ADS_FILE"
+    echo "✗ Screen database not found: $DB_FILE"
 fi
 echo
 
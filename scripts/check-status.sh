@@ -27,7 +27,9 @@ echo ""
 
 # Check configuration
 echo "⚙️  Configuration Status:"
-if [ -f ~/.mnemocast/identity.json ]; then
+if [ -f ~/.mnemocast/mnemocast.db ]; then
+    echo "   ✅ Screen database exists (identity, playlists, events)"
+elif [ -f ~/.mnemocast/identity.json ]; then
     echo "   ✅ Identity file exists"
     if command -v jq &> /dev/null; then
         SCREEN_ID=$(jq -r '.id' ~/.mnemocast/identity.json 2>/dev/null)